@@ -0,0 +1,267 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package vpnctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/backend"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// The control API is plain HTTP over the daemon's unix socket:
+//
+//	GET  /v1/status      current tunnel state and desired profile
+//	POST /v1/connect     {"profile": "dev"} sets the desired profile and connects
+//	POST /v1/disconnect  clears the desired profile and disconnects
+//	POST /v1/kill        kills the backend's desktop client
+//
+// Every response is an apiResponse; failures carry the error text. The daemon
+// connects with the credential from its own store, so no secret crosses the
+// socket and the second factor (push answer or TOTP code) is the profile's.
+
+// apiRequest is the body of POST /v1/connect.
+type apiRequest struct {
+	Profile string `json:"profile"`
+}
+
+// apiResponse is returned by every endpoint.
+type apiResponse struct {
+	State          *model.VPNState `json:"state,omitempty"`
+	DesiredProfile string          `json:"desired_profile,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// listenControlSocket listens on the socket path with owner-only permissions.
+// A socket left behind by a crashed daemon is replaced; a live one is an error.
+func listenControlSocket(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("creating control socket directory: %w", err)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a vpnctl daemon is already listening on %s", path)
+	}
+	os.Remove(path)
+
+	// The socket is created with the umask's permissions, so it is bound in a
+	// private directory, restricted there and only then moved into place.
+	private, err := os.MkdirTemp(filepath.Dir(path), ".sock")
+	if err != nil {
+		return nil, fmt.Errorf("creating control socket directory: %w", err)
+	}
+	defer os.RemoveAll(private)
+	bound := filepath.Join(private, "s")
+	listener, err := net.Listen("unix", bound)
+	if err != nil {
+		return nil, fmt.Errorf("listening on control socket %s: %w", path, err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false) // the daemon removes the socket when it stops
+	if err := os.Chmod(bound, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("restricting control socket permissions: %w", err)
+	}
+	if err := os.Rename(bound, path); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("listening on control socket %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// apiServer routes the control API to the daemon.
+func (d *Daemon) apiServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", d.handleStatus)
+	mux.HandleFunc("POST /v1/connect", d.handleConnect)
+	mux.HandleFunc("POST /v1/disconnect", d.handleDisconnect)
+	mux.HandleFunc("POST /v1/kill", d.handleKill)
+	return &http.Server{Handler: mux}
+}
+
+func (d *Daemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	b, desired := d.snapshot()
+	state, err := statusOf(r.Context(), b)
+	if err != nil {
+		logger.Errorf("retrieving VPN status: %v", err)
+	}
+	writeAPI(w, http.StatusOK, apiResponse{State: state, DesiredProfile: desired})
+}
+
+func (d *Daemon) handleConnect(w http.ResponseWriter, r *http.Request) {
+	var req apiRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPI(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	profile, err := config.ResolveProfile(req.Profile)
+	if err != nil {
+		writeAPI(w, http.StatusBadRequest, apiResponse{Error: err.Error()})
+		return
+	}
+	b, err := d.backendFor(profile)
+	if err != nil {
+		writeAPI(w, http.StatusBadRequest, apiResponse{Error: err.Error()})
+		return
+	}
+
+	credential := &model.CREDENTIAL_FOR_LOGIN{}
+	if b.Capabilities().Credentials {
+		if credential, err = d.credential(profile); err != nil {
+			writeAPI(w, http.StatusBadRequest, apiResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	logger.Infof("control API: connect profile %v", profile.Name)
+	d.connectMu.Lock()
+	defer d.connectMu.Unlock()
+	d.mu.Lock()
+	d.profile, d.backend = profile, b
+	d.authFailures, d.breakerOpen = 0, time.Time{} // an explicit request resets the breaker
+	d.mu.Unlock()
+	if err := d.connect(b, credential, profile); err != nil {
+		writeAPI(w, http.StatusBadGateway, apiResponse{DesiredProfile: d.desiredProfile(), Error: err.Error()})
+		return
+	}
+	state, _ := statusOf(r.Context(), b)
+	writeAPI(w, http.StatusOK, apiResponse{State: state, DesiredProfile: d.desiredProfile()})
+}
+
+func (d *Daemon) handleDisconnect(w http.ResponseWriter, r *http.Request) {
+	logger.Infof("control API: disconnect")
+	// wait for a connect in flight, so it cannot come up after the disconnect
+	d.connectMu.Lock()
+	defer d.connectMu.Unlock()
+	d.mu.Lock()
+	d.profile = nil
+	b := d.backend
+	d.mu.Unlock()
+	if err := disconnectWith(r.Context(), b); err != nil {
+		writeAPI(w, http.StatusBadGateway, apiResponse{Error: err.Error()})
+		return
+	}
+	state, _ := statusOf(r.Context(), b)
+	writeAPI(w, http.StatusOK, apiResponse{State: state})
+}
+
+func (d *Daemon) handleKill(w http.ResponseWriter, r *http.Request) {
+	logger.Infof("control API: kill")
+	b, desired := d.snapshot()
+	if err := killGUIOf(b); err != nil {
+		writeAPI(w, http.StatusBadGateway, apiResponse{Error: err.Error()})
+		return
+	}
+	writeAPI(w, http.StatusOK, apiResponse{DesiredProfile: desired})
+}
+
+// snapshot returns the current backend and desired profile name, so handlers
+// do not hold d.mu while they talk to the backend.
+func (d *Daemon) snapshot() (backend.Backend, string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.profile == nil {
+		return d.backend, ""
+	}
+	return d.backend, d.profile.Name
+}
+
+func (d *Daemon) desiredProfile() string {
+	_, name := d.snapshot()
+	return name
+}
+
+func writeAPI(w http.ResponseWriter, code int, resp apiResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// apiClient talks to a running daemon over its control socket.
+type apiClient struct {
+	http *http.Client
+}
+
+// dialDaemon returns a client for the daemon listening on [daemon] socket,
+// or nil when no daemon is running.
+func dialDaemon() *apiClient {
+	path, err := middleware.ExpandPath(config.DAEMON_SOCKET)
+	if err != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil
+	}
+	conn.Close()
+	return &apiClient{http: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}}}
+}
+
+// call performs one API request; non-2xx responses are returned as errors.
+func (c *apiClient) call(method, path string, body any) (*apiResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, "http://vpnctl"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling vpnctl daemon: %w", err)
+	}
+	defer res.Body.Close()
+
+	var resp apiResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("reading vpnctl daemon response: %w", err)
+	}
+	if res.StatusCode/100 != 2 {
+		if resp.Error == "" {
+			resp.Error = res.Status
+		}
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+func (c *apiClient) Status() (*apiResponse, error) {
+	return c.call(http.MethodGet, "/v1/status", nil)
+}
+
+func (c *apiClient) Connect(profile string) (*apiResponse, error) {
+	return c.call(http.MethodPost, "/v1/connect", apiRequest{Profile: profile})
+}
+
+func (c *apiClient) Disconnect() (*apiResponse, error) {
+	return c.call(http.MethodPost, "/v1/disconnect", nil)
+}
+
+func (c *apiClient) Kill() (*apiResponse, error) {
+	return c.call(http.MethodPost, "/v1/kill", nil)
+}
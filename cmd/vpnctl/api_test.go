@@ -0,0 +1,171 @@
+package vpnctl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/backend"
+	"github.com/goo-apps/vpnctl/internal/handler"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando/go-keyring"
+)
+
+// startTestDaemon runs an idle daemon on a fake backend behind a temporary control socket.
+func startTestDaemon(t *testing.T) (*Daemon, *fakeBackend) {
+	t.Helper()
+	setupFakeVPN(t, "")
+	keyring.MockInit()
+
+	// unix socket paths are short, so stay out of the long test temp dir names
+	dir, err := os.MkdirTemp("", "vpnctl")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	config.DAEMON_SOCKET = filepath.Join(dir, "vpnctl.sock")
+
+	fake := &credentialBackend{fakeBackend{state: model.StateDisconnected}}
+	d, _, _ := testDaemon(t, model.StateDisconnected)
+	d.profile, d.backend, d.socket = nil, fake, config.DAEMON_SOCKET
+	d.pollInterval = time.Hour
+	d.backendFor = func(*model.Profile) (backend.Backend, error) { return fake, nil }
+	d.connect = func(b backend.Backend, credential *model.CREDENTIAL_FOR_LOGIN, profile *model.Profile) error {
+		fake.connected, fake.credential = profile, credential
+		fake.state = model.StateConnected
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	for i := 0; i < 50 && dialDaemon() == nil; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	return d, &fake.fakeBackend
+}
+
+func TestControlAPI_CLIGoesThroughDaemon(t *testing.T) {
+	d, fake := startTestDaemon(t)
+
+	info, err := os.Stat(config.DAEMON_SOCKET)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.Equal(t, ExitDisconnected, Status("json"))
+
+	origPrompt := promptCredential
+	promptCredential = func(*model.Profile) (*model.CREDENTIAL_FOR_LOGIN, error) {
+		t.Error("the CLI asked for a credential the daemon does not use")
+		return testCredential(), nil
+	}
+	t.Cleanup(func() { promptCredential = origPrompt })
+	assert.NoError(t, Connect("dev"))
+	assert.Equal(t, "dev", fake.connected.Name)
+	assert.Equal(t, "pass", fake.credential.Password.Reveal(), "the daemon logs in with its stored credential")
+	assert.Equal(t, "dev", d.desiredProfile())
+	assert.Equal(t, ExitConnected, Status("json"))
+
+	DisconnectWithKillPid()
+	assert.True(t, fake.disconnected)
+	assert.Equal(t, "", d.desiredProfile())
+}
+
+func TestControlAPI_ConnectsWithTheProfilesSecondFactor(t *testing.T) {
+	d, fake := startTestDaemon(t)
+	keyring.MockInit()
+	origStore := config.CREDENTIAL_STORE
+	config.CREDENTIAL_STORE = "keyring"
+	t.Cleanup(func() { config.CREDENTIAL_STORE = origStore })
+	d.credential = handler.GetStoredCredential
+
+	origProfiles := config.VPN_PROFILES
+	config.VPN_PROFILES = append(slices.Clone(origProfiles), model.Profile{
+		Name: "lab", Host: "LAB", MFA: "totp",
+	})
+	t.Cleanup(func() { config.VPN_PROFILES = origProfiles })
+
+	cred := model.CREDENTIAL_FOR_LOGIN{Username: "user", Password: "pass", Push: "push"}
+	dev, _ := config.ResolveProfile("dev")
+	lab, _ := config.ResolveProfile("lab")
+	assert.NoError(t, handler.StoreCredential(handler.ProfileAccount(dev), cred))
+	assert.NoError(t, handler.StoreCredential(handler.ProfileAccount(lab), cred))
+	_, err := handler.SetTOTPSeed(handler.ProfileAccount(lab), "JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+
+	assert.NoError(t, Connect("dev"))
+	assert.Equal(t, "dev", fake.connected.Name)
+	assert.Equal(t, "push", fake.credential.SecondPassword())
+	assert.Equal(t, "y", fake.credential.YFlag)
+
+	assert.NoError(t, Connect("lab"))
+	assert.Equal(t, "lab", fake.connected.Name)
+	assert.Regexp(t, `^\d{6}$`, fake.credential.SecondPassword())
+}
+
+func TestControlAPI_DisconnectWaitsForConnectInFlight(t *testing.T) {
+	d, fake := startTestDaemon(t)
+	connecting, release := make(chan struct{}), make(chan struct{})
+	connect := d.connect
+	d.connect = func(b backend.Backend, credential *model.CREDENTIAL_FOR_LOGIN, profile *model.Profile) error {
+		close(connecting)
+		<-release
+		return connect(b, credential, profile)
+	}
+	c := dialDaemon()
+	connected, disconnected := make(chan error), make(chan error)
+	go func() {
+		_, err := c.Connect("dev")
+		connected <- err
+	}()
+	<-connecting
+	go func() {
+		_, err := c.Disconnect()
+		disconnected <- err
+	}()
+
+	select {
+	case <-disconnected:
+		t.Fatal("the disconnect ran while the connect was in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	assert.NoError(t, <-connected)
+	assert.NoError(t, <-disconnected)
+	assert.True(t, fake.disconnected, "the disconnect follows the connect")
+	assert.Equal(t, "", d.desiredProfile())
+
+	entries, err := os.ReadDir(filepath.Dir(config.DAEMON_SOCKET))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "only the socket is left in its directory")
+}
+
+func TestControlAPI_Errors(t *testing.T) {
+	startTestDaemon(t)
+	c := dialDaemon()
+	assert.NotNil(t, c)
+
+	_, err := c.Connect("nope")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "nope")
+
+	_, err = c.Kill()
+	assert.EqualError(t, err, errNoGUI.Error())
+}
+
+func TestControlAPI_SecondDaemonRefused(t *testing.T) {
+	startTestDaemon(t)
+	_, err := listenControlSocket(config.DAEMON_SOCKET)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already listening")
+}
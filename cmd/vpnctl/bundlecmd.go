@@ -0,0 +1,112 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package vpnctl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/goo-apps/vpnctl/internal/handler"
+	"golang.org/x/term"
+)
+
+// readBundlePassphrase asks for the passphrase of a bundle; replaced in tests.
+var readBundlePassphrase = func(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// Export writes the profiles, credentials, their expiry dates and the connection
+// history to out, encrypted with a passphrase chosen for the bundle.
+func Export(out string) error {
+	bundle, err := handler.ExportBundle()
+	if err != nil {
+		return err
+	}
+	passphrase, err := readBundlePassphrase("Choose a passphrase for the bundle: ")
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return fmt.Errorf("the bundle needs a passphrase")
+	}
+	repeated, err := readBundlePassphrase("Repeat passphrase: ")
+	if err != nil {
+		return err
+	}
+	if passphrase != repeated {
+		return fmt.Errorf("passphrases do not match")
+	}
+	data, err := handler.SealBundle(bundle, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, data, 0600); err != nil {
+		return fmt.Errorf("writing bundle: %w", err)
+	}
+	fmt.Printf("Exported %d profiles, %d credentials and %d sessions to %s\n",
+		strings.Count(bundle.Profiles, "[[profile]]"), len(bundle.Records), len(bundle.Sessions), out)
+	return nil
+}
+
+// Import merges a bundle written by Export into the local state. The changes are
+// shown first and made once confirmed, or right away with yes.
+func Import(path string, yes bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading bundle: %w", err)
+	}
+	passphrase, err := readBundlePassphrase("Enter the passphrase of the bundle: ")
+	if err != nil {
+		return err
+	}
+	bundle, err := handler.OpenBundle(data, passphrase)
+	if err != nil {
+		return err
+	}
+	plan, err := handler.PlanImport(bundle)
+	if err != nil {
+		return err
+	}
+	printImportPlan(os.Stdout, plan)
+	if plan.Empty() {
+		return nil
+	}
+	if !yes && !confirm(os.Stdin, "Merge these changes? [y/N]: ") {
+		fmt.Println("Nothing imported")
+		return nil
+	}
+	if err := handler.ApplyImport(plan); err != nil {
+		return err
+	}
+	fmt.Println("Imported the bundle")
+	return nil
+}
+
+func printImportPlan(w io.Writer, plan *handler.ImportPlan) {
+	for _, change := range plan.Changes {
+		fmt.Fprintln(w, change)
+	}
+	if plan.Empty() {
+		fmt.Fprintln(w, "Nothing to import, this machine already has everything in the bundle")
+	}
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func confirm(r io.Reader, question string) bool {
+	fmt.Print(question)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,26 @@
+package vpnctl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/goo-apps/vpnctl/internal/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintImportPlan(t *testing.T) {
+	var buf bytes.Buffer
+	printImportPlan(&buf, &handler.ImportPlan{Changes: []handler.BundleChange{
+		{Kind: handler.ChangeKeep, Item: "profile intra", Detail: "differs, keeping the local one"},
+	}})
+	assert.Equal(t, "! profile intra (differs, keeping the local one)\n"+
+		"Nothing to import, this machine already has everything in the bundle\n", buf.String())
+}
+
+func TestConfirm(t *testing.T) {
+	assert.True(t, confirm(strings.NewReader("y\n"), ""))
+	assert.True(t, confirm(strings.NewReader("Yes\n"), ""))
+	assert.False(t, confirm(strings.NewReader("\n"), ""))
+	assert.False(t, confirm(strings.NewReader(""), ""))
+}
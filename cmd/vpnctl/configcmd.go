@@ -0,0 +1,82 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package vpnctl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/goo-apps/vpnctl/config"
+)
+
+// ExitConfigInvalid is the `vpnctl config validate` exit code when problems were found.
+const ExitConfigInvalid = 1
+
+// maskedValue replaces secrets in `vpnctl config show` and `get`.
+const maskedValue = `"********"`
+
+// ConfigShow prints the merged configuration, one key per line, optionally with
+// the file and line, environment variable or flag each value came from.
+func ConfigShow(origin bool) {
+	printSettings(os.Stdout, config.Settings(), origin)
+}
+
+// ConfigGet prints one key, or every key of a table, with its origin.
+func ConfigGet(key string) error {
+	settings, err := config.Lookup(key)
+	if err != nil {
+		return err
+	}
+	printSettings(os.Stdout, settings, true)
+	return nil
+}
+
+// ConfigSet writes a value to the user configuration file. It warns when an
+// environment variable or flag still overrides the key.
+func ConfigSet(key, value string) error {
+	path, err := config.SetUserValue(key, value)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Set %s in %s\n", key, path)
+	if origin := config.OriginOf(key); origin.Source == config.OriginEnv || origin.Source == config.OriginFlag {
+		fmt.Printf("Note: %s is currently overridden by %s\n", key, origin)
+	}
+	return nil
+}
+
+// ConfigValidate checks the configuration files, or only the given file, and
+// prints every problem with its location. It returns ExitConfigInvalid when
+// there were problems.
+func ConfigValidate(path string) int {
+	problems := config.Validate(path)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return ExitConfigInvalid
+	}
+	fmt.Println("Configuration is valid")
+	return 0
+}
+
+func printSettings(w io.Writer, settings []config.Setting, origin bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range settings {
+		value := config.FormatValue(s.Value)
+		if config.IsSecret(s.Key) && s.Value != "" {
+			value = maskedValue
+		}
+		if origin {
+			fmt.Fprintf(tw, "%s = %s\t# %s\n", s.Key, value, s.Origin)
+		} else {
+			fmt.Fprintf(tw, "%s = %s\n", s.Key, value)
+		}
+	}
+	tw.Flush()
+}
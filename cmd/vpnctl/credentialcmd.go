@@ -0,0 +1,120 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package vpnctl
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/handler"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"golang.org/x/term"
+)
+
+// errPasswordMismatch is returned when the password entered to reveal a credential
+// is not the stored one.
+var errPasswordMismatch = errors.New("the password does not match the stored credential")
+
+// CredentialList prints the stored credentials, the profiles using each one, the
+// usernames and expiry dates as a table (default) or json. Passwords and seeds are
+// never printed.
+func CredentialList(output string) error {
+	entries, err := handler.ListCredentials()
+	if err != nil {
+		return err
+	}
+	return printCredentials(os.Stdout, entries, output, time.Now())
+}
+
+func printCredentials(w io.Writer, entries []handler.CredentialEntry, output string, now time.Time) error {
+	switch output {
+	case "json":
+		if entries == nil {
+			entries = []handler.CredentialEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling credentials to JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+	case "table", "":
+		if len(entries) == 0 {
+			fmt.Fprintln(w, "No credential stored, run `vpnctl credential update`")
+			return nil
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ACCOUNT\tPROFILES\tUSERNAME\tMFA\tEXPIRES")
+		for _, e := range entries {
+			expires := "-"
+			if e.Expiry != nil {
+				days := int(e.Expiry.Sub(now).Hours() / 24)
+				expires = fmt.Sprintf("%s (%d days)", e.Expiry.Format("2006-01-02"), days)
+				if !now.Before(*e.Expiry) {
+					expires = e.Expiry.Format("2006-01-02") + " (expired)"
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Account, dash(strings.Join(e.Profiles, ", ")), e.Username, e.MFA, expires)
+		}
+		tw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q, use json or table", output)
+	}
+	return nil
+}
+
+// CredentialFetch prints the account's credential as json with the password
+// masked. With reveal the plaintext is printed, once the password has been entered
+// again.
+func CredentialFetch(account string, reveal bool) error {
+	cred, err := handler.GetCredential(account)
+	if err != nil {
+		return err
+	}
+	if reveal {
+		fmt.Fprint(os.Stderr, "Enter the password to reveal it: ")
+		entered, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return fmt.Errorf("reading the password: %w", err)
+		}
+		if err := checkPassword(cred.Password, entered); err != nil {
+			return err
+		}
+	}
+	return printCredential(os.Stdout, cred, reveal)
+}
+
+// checkPassword compares the entered password with the stored one in constant
+// time.
+func checkPassword(stored model.Secret, entered []byte) error {
+	if subtle.ConstantTimeCompare([]byte(stored.Reveal()), entered) != 1 {
+		return errPasswordMismatch
+	}
+	return nil
+}
+
+func printCredential(w io.Writer, cred model.CREDENTIAL_FOR_LOGIN, reveal bool) error {
+	var v interface{} = cred
+	if reveal {
+		v = struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}{cred.Username, cred.Password.Reveal()}
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling credential to JSON: %w", err)
+	}
+	fmt.Fprintln(w, string(data))
+	return nil
+}
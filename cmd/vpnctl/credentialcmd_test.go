@@ -0,0 +1,53 @@
+package vpnctl
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/handler"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintCredentials(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+	soon := time.Date(2026, 5, 11, 0, 0, 0, 0, time.Local)
+	past := time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)
+	entries := []handler.CredentialEntry{
+		{Account: "vpnctl", Profiles: []string{"intra"}, Username: "alice", MFA: "push", Expiry: &soon},
+		{Account: "dev", Profiles: []string{"dev"}, Username: "contractor", MFA: "totp", Expiry: &past},
+		{Account: "lab", Username: "bob", MFA: "none"},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, printCredentials(&buf, entries, "table", now))
+	assert.Equal(t, ""+
+		"ACCOUNT  PROFILES  USERNAME    MFA   EXPIRES\n"+
+		"vpnctl   intra     alice       push  2026-05-11 (9 days)\n"+
+		"dev      dev       contractor  totp  2026-04-01 (expired)\n"+
+		"lab      -         bob         none  -\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, printCredentials(&buf, nil, "json", now))
+	assert.Equal(t, "[]\n", buf.String())
+	assert.Error(t, printCredentials(&buf, nil, "csv", now))
+}
+
+func TestPrintCredential_MasksUnlessRevealed(t *testing.T) {
+	cred := model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "s3cret", Push: "push"}
+
+	var buf bytes.Buffer
+	assert.NoError(t, printCredential(&buf, cred, false))
+	assert.Equal(t, "{\n  \"username\": \"alice\",\n  \"password\": \"********\"\n}\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, printCredential(&buf, cred, true))
+	assert.Equal(t, "{\n  \"username\": \"alice\",\n  \"password\": \"s3cret\"\n}\n", buf.String())
+}
+
+func TestCheckPassword(t *testing.T) {
+	assert.NoError(t, checkPassword("s3cret", []byte("s3cret")))
+	assert.ErrorIs(t, checkPassword("s3cret", []byte("s3cre")), errPasswordMismatch)
+	assert.ErrorIs(t, checkPassword("s3cret", nil), errPasswordMismatch)
+}
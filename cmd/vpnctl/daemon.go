@@ -0,0 +1,240 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package vpnctl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/backend"
+	"github.com/goo-apps/vpnctl/internal/handler"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// Daemon keeps one profile connected. It polls the backend status and re-runs the
+// connect flow with the stored credential whenever the tunnel is down. Reconnects
+// are rate limited, and a circuit breaker stops them after repeated authentication
+// failures so a changed password does not lock the account.
+type Daemon struct {
+	mu        sync.Mutex // guards the fields below, never held while a connect attempt runs
+	connectMu sync.Mutex // held while a connect or disconnect runs, so they never overlap
+
+	profile *model.Profile // desired profile, nil while the desired state is disconnected
+	backend backend.Backend
+	socket  string
+	metrics string // [daemon] metrics_address, empty when metrics are off
+
+	pollInterval         time.Duration
+	minReconnectInterval time.Duration
+	maxReconnectsPerHour int
+	authFailureLimit     int
+	breakerCooldown      time.Duration
+
+	// credential, connect and backendFor are replaced in tests
+	credential func(*model.Profile) (*model.CREDENTIAL_FOR_LOGIN, error)
+	connect    func(backend.Backend, *model.CREDENTIAL_FOR_LOGIN, *model.Profile) error
+	backendFor func(*model.Profile) (backend.Backend, error)
+	now        func() time.Time
+
+	reconnects   []time.Time // reconnect runs within the last hour
+	authFailures int         // consecutive authentication failures
+	breakerOpen  time.Time   // when the breaker tripped, zero while closed
+}
+
+// NewDaemon returns a daemon holding the given profile (name or alias) connected,
+// configured from the [daemon] section. With an empty name it starts without a
+// desired profile and waits for a connect request on the control socket.
+func NewDaemon(name string) (*Daemon, error) {
+	socket, err := middleware.ExpandPath(config.DAEMON_SOCKET)
+	if err != nil {
+		return nil, err
+	}
+	d := &Daemon{
+		backend:              currentBackend(),
+		socket:               socket,
+		metrics:              config.DAEMON_METRICS_ADDRESS,
+		pollInterval:         config.DAEMON_POLL_INTERVAL,
+		minReconnectInterval: config.DAEMON_MIN_RECONNECT_INTERVAL,
+		maxReconnectsPerHour: config.DAEMON_MAX_RECONNECTS_PER_HOUR,
+		authFailureLimit:     config.DAEMON_AUTH_FAILURE_LIMIT,
+		breakerCooldown:      config.DAEMON_BREAKER_COOLDOWN,
+		credential:           handler.GetStoredCredential,
+		connect:              connectWithRetries,
+		backendFor:           backend.ForProfile,
+		now:                  time.Now,
+	}
+	if name != "" {
+		profile, err := config.ResolveProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		if d.backend, err = d.backendFor(profile); err != nil {
+			return nil, err
+		}
+		d.profile = profile
+	}
+	return d, nil
+}
+
+// Run serves the control socket, and the metrics endpoint when enabled, and
+// supervises the tunnel until the context is cancelled.
+func (d *Daemon) Run(ctx context.Context) error {
+	listener, err := listenControlSocket(d.socket)
+	if err != nil {
+		return err
+	}
+	server := d.apiServer()
+	serveErr := make(chan error, 2)
+	go func() { serveErr <- server.Serve(listener) }()
+	defer func() {
+		server.Close()
+		os.Remove(d.socket)
+	}()
+
+	if d.metrics != "" {
+		metricsListener, err := listenMetrics(d.metrics)
+		if err != nil {
+			return err
+		}
+		if open, err := middleware.GetOpenSession(); err == nil {
+			metrics.markConnected(open.Profile)
+		}
+		metricsSrv := metricsServer()
+		go func() { serveErr <- metricsSrv.Serve(metricsListener) }()
+		defer metricsSrv.Close()
+		logger.Infof("serving Prometheus metrics on http://%v/metrics", metricsListener.Addr())
+	}
+
+	if d.profile != nil {
+		logger.Infof("vpnctl daemon holding profile %v connected (poll every %s)", d.profile.Name, d.pollInterval)
+	} else {
+		logger.Infof("vpnctl daemon waiting for a connect request on %v", d.socket)
+	}
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		d.check(ctx)
+		select {
+		case <-ctx.Done():
+			logger.Infof("vpnctl daemon stopped")
+			return nil
+		case err := <-serveErr:
+			return fmt.Errorf("daemon server: %w", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs one supervision step: it reconnects when the tunnel is down and
+// neither the rate limit nor the circuit breaker holds it back.
+// It reports whether a reconnect was attempted. d.mu is released while the
+// reconnect runs, so the control API keeps answering during backoff and agent
+// recovery.
+func (d *Daemon) check(ctx context.Context) bool {
+	if !d.connectMu.TryLock() {
+		return false // a connect request from the control API is running
+	}
+	defer d.connectMu.Unlock()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.profile == nil {
+		return false
+	}
+	// statusOf also ends the session of a dropped tunnel, even when the rate
+	// limit or the breaker keeps the daemon from reconnecting
+	state, err := statusOf(ctx, d.backend)
+	if err != nil {
+		logger.Warningf("daemon status check failed: %v", err)
+	}
+	if state != nil && state.State == model.StateConnected {
+		return false
+	}
+	if state != nil && state.State == model.StateConnecting {
+		return false // the client is already working on it
+	}
+
+	now := d.now()
+	if !d.breakerOpen.IsZero() {
+		if d.breakerCooldown <= 0 || now.Before(d.breakerOpen.Add(d.breakerCooldown)) {
+			logger.Warningf("tunnel for profile %v is down, but reconnects are suspended after %d authentication failures", d.profile.Name, d.authFailures)
+			return false
+		}
+		logger.Infof("circuit breaker cooldown passed, trying profile %v again", d.profile.Name)
+	}
+	if !d.allowReconnect(now) {
+		return false
+	}
+
+	credential := &model.CREDENTIAL_FOR_LOGIN{}
+	if d.backend.Capabilities().Credentials {
+		if credential, err = d.credential(d.profile); err != nil {
+			logger.Errorf("daemon cannot reconnect profile %v: %v", d.profile.Name, err)
+			return false
+		}
+	}
+
+	logger.Infof("tunnel for profile %v is down, reconnecting", d.profile.Name)
+	d.reconnects = append(d.reconnects, now)
+	metrics.observeReconnect()
+	profile, b := d.profile, d.backend
+	d.mu.Unlock()
+	err = d.connect(b, credential, profile)
+	d.mu.Lock()
+	if d.profile != profile {
+		// a disconnect request arrived meanwhile and wins over the reconnect
+		if d.profile == nil && err == nil {
+			if err := disconnectWith(ctx, b); err != nil {
+				logger.Errorf("daemon disconnect of profile %v failed: %v", profile.Name, err)
+			}
+		}
+		return true
+	}
+	switch {
+	case err == nil:
+		d.authFailures = 0
+		d.breakerOpen = time.Time{}
+		logger.Infof("daemon reconnected profile %v", d.profile.Name)
+	case backend.Classify(err) == model.FailureAuth:
+		d.authFailures++
+		logger.Errorf("daemon reconnect of profile %v was rejected (%d/%d): %v", d.profile.Name, d.authFailures, d.authFailureLimit, err)
+		if d.authFailureLimit > 0 && d.authFailures >= d.authFailureLimit {
+			d.breakerOpen = now
+			logger.Errorf("circuit breaker open: not reconnecting profile %v after %d authentication failures", d.profile.Name, d.authFailures)
+		}
+	default:
+		logger.Errorf("daemon reconnect of profile %v failed: %v", d.profile.Name, err)
+	}
+	return true
+}
+
+// allowReconnect applies the rate limit: reconnects are at least
+// minReconnectInterval apart and at most maxReconnectsPerHour per hour.
+func (d *Daemon) allowReconnect(now time.Time) bool {
+	recent := d.reconnects[:0]
+	for _, at := range d.reconnects {
+		if now.Sub(at) < time.Hour {
+			recent = append(recent, at)
+		}
+	}
+	d.reconnects = recent
+
+	if n := len(d.reconnects); n > 0 && now.Sub(d.reconnects[n-1]) < d.minReconnectInterval {
+		logger.Infof("tunnel for profile %v is down, next reconnect in %s", d.profile.Name,
+			d.reconnects[n-1].Add(d.minReconnectInterval).Sub(now).Truncate(time.Second))
+		return false
+	}
+	if d.maxReconnectsPerHour > 0 && len(d.reconnects) >= d.maxReconnectsPerHour {
+		logger.Warningf("tunnel for profile %v is down, but %d reconnects in the last hour hit the limit", d.profile.Name, len(d.reconnects))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package vpnctl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/backend"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDaemon supervises the intra profile on a fake backend with a fake clock.
+// Every reconnect fails with the next queued error (nil once the queue is empty).
+func testDaemon(t *testing.T, state string, errs ...error) (*Daemon, *time.Time, *int) {
+	t.Helper()
+	profile, _ := config.ResolveProfile("intra")
+	now := time.Date(2025, 6, 19, 2, 0, 0, 0, time.UTC)
+	calls := 0
+	d := &Daemon{
+		profile:              profile,
+		backend:              &fakeBackend{state: state},
+		pollInterval:         time.Second,
+		minReconnectInterval: time.Minute,
+		maxReconnectsPerHour: 3,
+		authFailureLimit:     2,
+		breakerCooldown:      30 * time.Minute,
+		credential: func(*model.Profile) (*model.CREDENTIAL_FOR_LOGIN, error) {
+			return testCredential(), nil
+		},
+		connect: func(backend.Backend, *model.CREDENTIAL_FOR_LOGIN, *model.Profile) error {
+			calls++
+			if len(errs) == 0 {
+				return nil
+			}
+			err := errs[0]
+			errs = errs[1:]
+			return err
+		},
+		now: func() time.Time { return now },
+	}
+	return d, &now, &calls
+}
+
+func TestDaemon_LeavesConnectedTunnelAlone(t *testing.T) {
+	d, _, calls := testDaemon(t, model.StateConnected)
+	assert.False(t, d.check(context.Background()))
+	assert.Equal(t, 0, *calls)
+}
+
+func TestDaemon_RateLimitsReconnects(t *testing.T) {
+	network := errors.New("network is unreachable")
+	d, now, calls := testDaemon(t, model.StateDisconnected, network, network, network, network)
+	ctx := context.Background()
+
+	assert.True(t, d.check(ctx))
+	assert.False(t, d.check(ctx), "second reconnect within min_reconnect_interval")
+
+	*now = now.Add(time.Minute)
+	assert.True(t, d.check(ctx))
+	*now = now.Add(time.Minute)
+	assert.True(t, d.check(ctx))
+	*now = now.Add(time.Minute)
+	assert.False(t, d.check(ctx), "max_reconnects_per_hour reached")
+	assert.Equal(t, 3, *calls)
+
+	*now = now.Add(time.Hour)
+	assert.True(t, d.check(ctx))
+}
+
+func TestDaemon_CircuitBreakerOnAuthFailures(t *testing.T) {
+	d, now, calls := testDaemon(t, model.StateDisconnected, backend.ErrAuthFailed, backend.ErrAuthFailed, backend.ErrAuthFailed)
+	ctx := context.Background()
+
+	assert.True(t, d.check(ctx))
+	*now = now.Add(time.Minute)
+	assert.True(t, d.check(ctx))
+	assert.False(t, d.breakerOpen.IsZero())
+
+	*now = now.Add(10 * time.Minute)
+	assert.False(t, d.check(ctx), "breaker is open")
+	assert.Equal(t, 2, *calls)
+
+	// half-open after the cooldown: one more rejection re-opens it
+	*now = now.Add(30 * time.Minute)
+	assert.True(t, d.check(ctx))
+	*now = now.Add(time.Minute)
+	assert.False(t, d.check(ctx))
+
+	// a success closes it again
+	*now = now.Add(30 * time.Minute)
+	assert.True(t, d.check(ctx))
+	assert.True(t, d.breakerOpen.IsZero())
+	assert.Equal(t, 0, d.authFailures)
+}
+
+func TestDaemon_EndsDroppedSessionWhileBreakerIsOpen(t *testing.T) {
+	setupFakeVPN(t, "")
+	resetMetrics(t)
+	d, now, calls := testDaemon(t, model.StateConnected)
+	recordSession(context.Background(), d.backend, d.profile, *now, 1, nil)
+	assert.Contains(t, scrape(metrics), "vpnctl_connected{profile=\"intra\"} 1\n")
+
+	d.backend.(*fakeBackend).state = model.StateDisconnected
+	d.authFailures, d.breakerOpen = 2, *now
+	assert.False(t, d.check(context.Background()))
+	assert.Equal(t, 0, *calls)
+
+	_, err := middleware.GetOpenSession()
+	assert.Error(t, err, "the dropped session is ended")
+	sessions, err := middleware.ListSessions(time.Time{}, "intra")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, model.SessionEndDropped, sessions[0].EndReason)
+	assert.Contains(t, scrape(metrics), "vpnctl_connected{profile=\"intra\"} 0\n")
+}
+
+func TestDaemon_ReconnectDoesNotBlockTheAPI(t *testing.T) {
+	d, _, _ := testDaemon(t, model.StateDisconnected)
+	connecting, release := make(chan struct{}), make(chan struct{})
+	d.connect = func(backend.Backend, *model.CREDENTIAL_FOR_LOGIN, *model.Profile) error {
+		close(connecting)
+		<-release
+		return nil
+	}
+	done := make(chan bool)
+	go func() { done <- d.check(context.Background()) }()
+
+	<-connecting
+	assert.Equal(t, "intra", d.desiredProfile(), "answered while the reconnect runs")
+	assert.False(t, d.check(context.Background()), "no second reconnect while one runs")
+	close(release)
+	assert.True(t, <-done)
+}
+
+func TestDaemon_NoStoredCredential(t *testing.T) {
+	d, _, calls := testDaemon(t, model.StateDisconnected)
+	d.backend = &credentialBackend{fakeBackend{state: model.StateDisconnected}}
+	d.credential = func(*model.Profile) (*model.CREDENTIAL_FOR_LOGIN, error) {
+		return nil, errors.New("no usable credential stored")
+	}
+	assert.False(t, d.check(context.Background()))
+	assert.Equal(t, 0, *calls)
+}
+
+// credentialBackend is a fakeBackend that needs a credential.
+type credentialBackend struct{ fakeBackend }
+
+func (c *credentialBackend) Capabilities() backend.Capabilities {
+	return backend.Capabilities{Credentials: true}
+}
+
+// guiBackend is a fakeBackend with a desktop client.
+type guiBackend struct {
+	fakeBackend
+	launched bool
+}
+
+func (g *guiBackend) Capabilities() backend.Capabilities { return backend.Capabilities{GUI: true} }
+func (g *guiBackend) LaunchGUI() error                   { g.launched = true; return nil }
+func (g *guiBackend) KillGUI() error                     { return nil }
+
+func TestDaemon_ReconnectOpensNoGUI(t *testing.T) {
+	setupFakeVPN(t, "")
+	d, _, _ := testDaemon(t, model.StateDisconnected)
+	gui := &guiBackend{fakeBackend: fakeBackend{state: model.StateDisconnected}}
+	d.backend, d.connect = gui, connectWithRetries
+
+	assert.True(t, d.check(context.Background()))
+	assert.Equal(t, "intra", gui.connected.Name)
+	assert.False(t, gui.launched, "a background reconnect opens no window")
+}
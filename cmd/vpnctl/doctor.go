@@ -0,0 +1,290 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package vpnctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/backend"
+	"github.com/goo-apps/vpnctl/internal/handler"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	servicediscovery "github.com/goo-apps/vpnctl/internal/service-discovery"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// Doctor check results.
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// ExitDoctorFailed is the `vpnctl doctor` exit code when a check failed.
+const ExitDoctorFailed = 1
+
+// credentialExpiryWarning is how close to its expiry the credential is reported,
+// the first of the reminders status and connect print.
+var credentialExpiryWarning = time.Duration(handler.ExpiryReminderDays[0]) * 24 * time.Hour
+
+// DoctorCheck is one line of the `vpnctl doctor` report.
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // ok, warn or fail
+	Detail string `json:"detail"`
+}
+
+// DoctorReport is the `vpnctl doctor --json` document.
+type DoctorReport struct {
+	Version string        `json:"vpnctl_version"`
+	OS      string        `json:"os"`
+	Checks  []DoctorCheck `json:"checks"`
+}
+
+// Doctor checks the environment vpnctl depends on and prints a checklist, or JSON
+// to attach to a ticket. It returns 0 when nothing failed (warnings included),
+// ExitDoctorFailed when a check failed and ExitUsage for an unknown output format.
+func Doctor(output string) int {
+	report := DoctorReport{
+		Version: config.APPLICATION_VERSION,
+		OS:      runtime.GOOS + "/" + runtime.GOARCH,
+		Checks:  runDoctor(context.Background()),
+	}
+	if err := printDoctor(os.Stdout, report, output); err != nil {
+		logger.Errorf("%v", err)
+		return ExitUsage
+	}
+	for _, check := range report.Checks {
+		if check.Status == CheckFail {
+			return ExitDoctorFailed
+		}
+	}
+	return 0
+}
+
+// runDoctor runs every check in report order.
+func runDoctor(ctx context.Context) []DoctorCheck {
+	checks := []DoctorCheck{checkConfigSource()}
+	binary, check := checkVPNBinary()
+	checks = append(checks, check, checkCiscoVersion(ctx, binary), checkGUI(), checkAgent(ctx))
+	return append(checks, checkKeyring(), checkCredentialStore(), checkDatabase(), checkCredentialExpiry(time.Now()))
+}
+
+func checkConfigSource() DoctorCheck {
+	if problems := config.Validate(""); len(problems) > 0 {
+		return DoctorCheck{Name: "config source", Status: CheckWarn, Detail: fmt.Sprintf("%s (%s, run `vpnctl config validate`)", config.CONFIG_SOURCE, problems[0])}
+	}
+	return DoctorCheck{Name: "config source", Status: CheckOK, Detail: config.CONFIG_SOURCE}
+}
+
+// checkVPNBinary looks for the resolved [vpn] binary_path and falls back to
+// discovering the Cisco client. It returns the binary to use for the version check.
+func checkVPNBinary() (string, DoctorCheck) {
+	check := DoctorCheck{Name: "vpn binary"}
+	if info, err := os.Stat(config.VPN_BINARY_PATH); err == nil && !info.IsDir() {
+		check.Status, check.Detail = CheckOK, withSource(config.VPN_BINARY_PATH, config.VPN_BINARY_SOURCE)
+		return config.VPN_BINARY_PATH, check
+	}
+	configured := config.VPN_BINARY_PATH
+	if configured == "" {
+		configured = "[vpn] binary_path"
+	}
+	detected, err := servicediscovery.DetectCiscoVPNPath()
+	if err != nil {
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("%s not found and discovery failed: %v", configured, err)
+		return "", check
+	}
+	check.Status = CheckWarn
+	check.Detail = fmt.Sprintf("%s not found, discovered %s (set [vpn] binary_path)", configured, detected)
+	return detected, check
+}
+
+func checkGUI() DoctorCheck {
+	check := DoctorCheck{Name: "cisco gui"}
+	if _, err := os.Stat(config.VPN_GUI_PATH); err != nil || config.VPN_GUI_PATH == "" {
+		check.Status, check.Detail = CheckWarn, "not found, `vpnctl gui` is unavailable (set [vpn] gui_path)"
+		return check
+	}
+	check.Status, check.Detail = CheckOK, withSource(config.VPN_GUI_PATH, config.VPN_GUI_SOURCE)
+	return check
+}
+
+// withSource appends where a resolved path came from, when known.
+func withSource(path, source string) string {
+	if source == "" {
+		return path
+	}
+	return fmt.Sprintf("%s (from %s)", path, source)
+}
+
+func checkCiscoVersion(ctx context.Context, binary string) DoctorCheck {
+	check := DoctorCheck{Name: "cisco client version"}
+	if binary == "" {
+		check.Status, check.Detail = CheckWarn, "skipped, no vpn binary"
+		return check
+	}
+	version, err := backend.CiscoVersion(ctx, binary)
+	if err != nil {
+		check.Status, check.Detail = CheckWarn, err.Error()
+		return check
+	}
+	check.Status, check.Detail = CheckOK, version
+	return check
+}
+
+// checkAgent reports whether vpnagentd runs, by process name or, where pgrep is
+// not available, by its IPC endpoint.
+func checkAgent(ctx context.Context) DoctorCheck {
+	check := DoctorCheck{Name: "vpnagentd"}
+	pids := backend.CiscoAgentPIDs(ctx)
+	reachErr := backend.CiscoAgentReachable(ctx)
+	switch {
+	case len(pids) > 0 && reachErr == nil:
+		check.Status = CheckOK
+		check.Detail = fmt.Sprintf("running (PID %s), accepting connections on %s", joinPIDs(pids), config.VPN_AGENT_ADDRESS)
+	case len(pids) > 0:
+		check.Status = CheckWarn
+		check.Detail = fmt.Sprintf("running (PID %s), but not reachable: %v", joinPIDs(pids), reachErr)
+	case reachErr == nil:
+		check.Status = CheckOK
+		check.Detail = fmt.Sprintf("accepting connections on %s", config.VPN_AGENT_ADDRESS)
+	default:
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("not running: %v", reachErr)
+	}
+	return check
+}
+
+func joinPIDs(pids []int) string {
+	parts := make([]string, len(pids))
+	for i, pid := range pids {
+		parts[i] = fmt.Sprint(pid)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkKeyring tests the OS keyring; it is only a warning when credentials are
+// kept in another store.
+func checkKeyring() DoctorCheck {
+	if err := handler.CheckKeyring(); err != nil {
+		if store, serr := handler.CredentialStore(); serr == nil && store.Name() != handler.StoreKeyring {
+			return DoctorCheck{Name: "keyring", Status: CheckWarn, Detail: fmt.Sprintf("%v (credentials use the %s store)", err, store.Name())}
+		}
+		return DoctorCheck{Name: "keyring", Status: CheckFail, Detail: err.Error()}
+	}
+	return DoctorCheck{Name: "keyring", Status: CheckOK, Detail: "set/get/delete round trip passed"}
+}
+
+func checkCredentialStore() DoctorCheck {
+	store, err := handler.CredentialStore()
+	if err != nil {
+		return DoctorCheck{Name: "credential store", Status: CheckFail, Detail: err.Error()}
+	}
+	detail := store.Name()
+	switch store.Name() {
+	case handler.StoreFile:
+		path, _ := middleware.ExpandPath(config.CREDENTIAL_FILE)
+		detail = fmt.Sprintf("file %s", path)
+	case handler.StoreEnv:
+		detail = fmt.Sprintf("env %s/%s", handler.EnvUsername, handler.EnvPassword)
+	}
+	if config.CREDENTIAL_STORE == handler.StoreAuto || config.CREDENTIAL_STORE == "" {
+		detail += " (auto)"
+	}
+	return DoctorCheck{Name: "credential store", Status: CheckOK, Detail: detail}
+}
+
+func checkDatabase() DoctorCheck {
+	path, _ := middleware.ExpandPath(config.SQLITE_DB_PATH)
+	if err := middleware.CheckSchema(); err != nil {
+		return DoctorCheck{Name: "sqlite database", Status: CheckFail, Detail: fmt.Sprintf("%s: %v", path, err)}
+	}
+	return DoctorCheck{Name: "sqlite database", Status: CheckOK, Detail: path}
+}
+
+func checkCredentialExpiry(now time.Time) DoctorCheck {
+	check := DoctorCheck{Name: "credential expiry"}
+	// only the recorded dates: reading the credentials could migrate them
+	entries, err := handler.CredentialExpiries()
+	if err != nil {
+		check.Status, check.Detail = CheckWarn, fmt.Sprintf("reading credential expiry: %v", err)
+		return check
+	}
+	if len(entries) == 0 {
+		check.Status, check.Detail = CheckWarn, "no credential expiry recorded, run `vpnctl credential update`"
+		return check
+	}
+
+	check.Status = CheckOK
+	var details []string
+	for _, entry := range entries {
+		status, detail := credentialExpiryOf(entry, now)
+		if len(entries) > 1 {
+			detail = entry.Account + " " + detail
+		}
+		details = append(details, detail)
+		if checkSeverity[status] > checkSeverity[check.Status] {
+			check.Status = status
+		}
+	}
+	check.Detail = strings.Join(details, "; ")
+	return check
+}
+
+// checkSeverity orders the check statuses, worst last.
+var checkSeverity = map[string]int{CheckOK: 0, CheckWarn: 1, CheckFail: 2}
+
+// credentialExpiryOf reports how close one stored credential is to its expiry.
+func credentialExpiryOf(entry handler.CredentialEntry, now time.Time) (string, string) {
+	if entry.Expiry == nil {
+		return CheckOK, "no expiry recorded"
+	}
+	date := entry.Expiry.Format("2006-01-02")
+	rotate := "vpnctl credential rotate"
+	if entry.Account != handler.DefaultAccount() && len(entry.Profiles) > 0 {
+		rotate += " --profile " + entry.Profiles[0]
+	}
+	left := entry.Expiry.Sub(now)
+	switch {
+	case left <= 0:
+		return CheckFail, fmt.Sprintf("expired on %s, run `%s`", date, rotate)
+	case left < credentialExpiryWarning:
+		return CheckWarn, fmt.Sprintf("expires on %s (in %d days)", date, int(left.Hours()/24))
+	default:
+		return CheckOK, fmt.Sprintf("expires on %s (in %d days)", date, int(left.Hours()/24))
+	}
+}
+
+// printDoctor renders the report as a checklist (default) or JSON.
+func printDoctor(w io.Writer, report DoctorReport, output string) error {
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling doctor report to JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+	case "table", "":
+		fmt.Fprintf(w, "vpnctl %s on %s\n", report.Version, report.OS)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, check := range report.Checks {
+			fmt.Fprintf(tw, "[%s]\t%s\t%s\n", check.Status, check.Name, check.Detail)
+		}
+		tw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q, use json or table", output)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package vpnctl
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/handler"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando/go-keyring"
+)
+
+func checksByName(checks []DoctorCheck) map[string]DoctorCheck {
+	byName := map[string]DoctorCheck{}
+	for _, c := range checks {
+		byName[c.Name] = c
+	}
+	return byName
+}
+
+func TestDoctor_Checks(t *testing.T) {
+	setupFakeVPN(t, "Cisco Secure Client (version 5.1.2.42) .\n  >> state: Disconnected")
+	keyring.MockInit()
+	agent, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer agent.Close()
+	origAgent := config.VPN_AGENT_ADDRESS
+	config.VPN_AGENT_ADDRESS = agent.Addr().String()
+	t.Cleanup(func() { config.VPN_AGENT_ADDRESS = origAgent })
+	assert.NoError(t, handler.StoreCredential(handler.DefaultAccount(), *testCredential()))
+	assert.NoError(t, middleware.SetExpiryToDB(config.KEYRING_SERVICE_NAME, time.Now().Add(5*24*time.Hour).Format("2006-01-02")))
+
+	checks := checksByName(runDoctor(context.Background()))
+	assert.Equal(t, CheckOK, checks["config source"].Status)
+	assert.Equal(t, CheckOK, checks["vpn binary"].Status)
+	assert.Equal(t, config.VPN_BINARY_PATH, checks["vpn binary"].Detail)
+	assert.Equal(t, "5.1.2.42", checks["cisco client version"].Detail)
+	assert.Equal(t, CheckOK, checks["vpnagentd"].Status)
+	assert.Equal(t, CheckOK, checks["keyring"].Status)
+	assert.Equal(t, CheckOK, checks["credential store"].Status)
+	assert.Equal(t, CheckOK, checks["sqlite database"].Status)
+	assert.Equal(t, CheckWarn, checks["credential expiry"].Status, "expires within 14 days")
+}
+
+func TestDoctor_Failures(t *testing.T) {
+	setupFakeVPN(t, "")
+	keyring.MockInit()
+	t.Setenv("PATH", t.TempDir()) // nothing to discover
+	config.VPN_BINARY_PATH = filepath.Join(t.TempDir(), "missing")
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed.Close()
+	origAgent := config.VPN_AGENT_ADDRESS
+	config.VPN_AGENT_ADDRESS = closed.Addr().String()
+	t.Cleanup(func() { config.VPN_AGENT_ADDRESS = origAgent })
+	assert.NoError(t, handler.StoreCredential(handler.DefaultAccount(), *testCredential()))
+	assert.NoError(t, handler.StoreCredential("profile:dev", *testCredential()))
+	assert.NoError(t, middleware.SetExpiryToDB(config.KEYRING_SERVICE_NAME, time.Now().Add(90*24*time.Hour).Format("2006-01-02")))
+	assert.NoError(t, middleware.SetExpiryToDB("profile:dev", "2020-01-01"))
+
+	checks := checksByName(runDoctor(context.Background()))
+	assert.Equal(t, CheckFail, checks["vpn binary"].Status)
+	assert.Equal(t, CheckWarn, checks["cisco client version"].Status)
+	assert.Equal(t, CheckFail, checks["credential expiry"].Status)
+	assert.Contains(t, checks["credential expiry"].Detail, "profile:dev expired on 2020-01-01, run `vpnctl credential rotate --profile dev`")
+}
+
+func TestDoctor_LeavesLegacyCredentialAlone(t *testing.T) {
+	setupFakeVPN(t, "")
+	keyring.MockInit()
+	password, err := handler.Encrypt("pass", config.KEYRING_ENCRYPTION_KEY)
+	assert.NoError(t, err)
+	legacy := "user\n" + password + "\npush\ny"
+	assert.NoError(t, keyring.Set(config.KEYRING_SERVICE_NAME, handler.DefaultAccount(), legacy))
+	assert.NoError(t, middleware.SetExpiryToDB(config.KEYRING_SERVICE_NAME, "2020-01-01"))
+
+	checks := checksByName(runDoctor(context.Background()))
+	assert.Equal(t, CheckFail, checks["credential expiry"].Status)
+
+	stored, err := keyring.Get(config.KEYRING_SERVICE_NAME, handler.DefaultAccount())
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, stored, "doctor does not migrate the entry")
+	_, err = keyring.Get(config.KEYRING_SERVICE_NAME, "vpnctl-data-key")
+	assert.ErrorIs(t, err, keyring.ErrNotFound, "doctor does not create the data key")
+}
+
+func TestPrintDoctor(t *testing.T) {
+	report := DoctorReport{Version: "v1.2.3", OS: "linux/amd64", Checks: []DoctorCheck{
+		{Name: "keyring", Status: CheckOK, Detail: "set/get/delete round trip passed"},
+		{Name: "vpnagentd", Status: CheckFail, Detail: "not running"},
+	}}
+
+	var table strings.Builder
+	assert.NoError(t, printDoctor(&table, report, "table"))
+	assert.Contains(t, table.String(), "vpnctl v1.2.3 on linux/amd64\n")
+	assert.Contains(t, table.String(), "[fail]  vpnagentd")
+
+	var out strings.Builder
+	assert.NoError(t, printDoctor(&out, report, "json"))
+	var decoded DoctorReport
+	assert.NoError(t, json.Unmarshal([]byte(out.String()), &decoded))
+	assert.Equal(t, report, decoded)
+
+	assert.Error(t, printDoctor(&out, report, "xml"))
+}
@@ -0,0 +1,127 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package vpnctl
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+// History prints the recorded VPN sessions started within the given window
+// (e.g. 7d, 12h, 30m), optionally for one profile (name or alias), as a table
+// (default), csv or json.
+func History(since, profile, output string) error {
+	window, err := parseSince(since)
+	if err != nil {
+		return err
+	}
+	if profile != "" {
+		p, err := config.ResolveProfile(profile)
+		if err != nil {
+			return err
+		}
+		profile = p.Name
+	}
+
+	sessions, err := middleware.ListSessions(time.Now().Add(-window), profile)
+	if err != nil {
+		return err
+	}
+	return printSessions(os.Stdout, sessions, output, time.Now())
+}
+
+// parseSince parses a look-back window. Go durations are accepted, plus a
+// "d" suffix for days.
+func parseSince(since string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(since, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid --since %q, use e.g. 7d or 12h", since)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(since)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid --since %q, use e.g. 7d or 12h", since)
+	}
+	return d, nil
+}
+
+// printSessions renders sessions in the requested output format.
+func printSessions(w io.Writer, sessions []model.Session, output string, now time.Time) error {
+	switch output {
+	case "json":
+		if sessions == nil {
+			sessions = []model.Session{}
+		}
+		data, err := json.MarshalIndent(sessions, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling history to JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "profile", "backend", "started_at", "ended_at", "duration_seconds", "end_reason",
+			"attempts", "error_class", "client_ip", "rx_bytes", "tx_bytes"})
+		for _, s := range sessions {
+			ended := ""
+			if s.EndedAt != nil {
+				ended = s.EndedAt.Format(time.RFC3339)
+			}
+			cw.Write([]string{
+				strconv.FormatInt(s.ID, 10), s.Profile, s.Backend, s.StartedAt.Format(time.RFC3339), ended,
+				strconv.FormatInt(int64(sessionDuration(s, now).Seconds()), 10), s.EndReason,
+				strconv.Itoa(s.Attempts), s.ErrorClass, s.ClientIP,
+				strconv.FormatInt(s.RxBytes, 10), strconv.FormatInt(s.TxBytes, 10),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "STARTED\tPROFILE\tBACKEND\tDURATION\tEND\tATTEMPTS\tERROR\tCLIENT IP\tRX/TX")
+		for _, s := range sessions {
+			end := s.EndReason
+			if s.EndedAt == nil {
+				end = "active"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%d/%d\n",
+				s.StartedAt.Local().Format("2006-01-02 15:04"), s.Profile, s.Backend,
+				sessionDuration(s, now).Truncate(time.Second), end, s.Attempts,
+				dash(s.ErrorClass), dash(s.ClientIP), s.RxBytes, s.TxBytes)
+		}
+		tw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q, use csv, json or table", output)
+	}
+	return nil
+}
+
+// sessionDuration is the session length, up to now for active sessions.
+func sessionDuration(s model.Session, now time.Time) time.Duration {
+	if s.EndedAt != nil {
+		return s.EndedAt.Sub(s.StartedAt)
+	}
+	return now.Sub(s.StartedAt)
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
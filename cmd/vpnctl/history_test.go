@@ -0,0 +1,87 @@
+package vpnctl
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/backend"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSince(t *testing.T) {
+	d, err := parseSince("7d")
+	assert.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, d)
+
+	d, err = parseSince("90m")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	_, err = parseSince("a week")
+	assert.Error(t, err)
+}
+
+func TestSessionHistory_ConnectAndDisconnect(t *testing.T) {
+	setupFakeVPN(t, "  >> state: Disconnected")
+	profile, _ := config.ResolveProfile("intra")
+
+	fake := &fakeBackend{state: model.StateDisconnected}
+	assert.NoError(t, connectWithRetries(fake, testCredential(), profile))
+
+	open, err := middleware.GetOpenSession()
+	assert.NoError(t, err)
+	assert.Equal(t, "intra", open.Profile)
+	assert.Equal(t, "fake", open.Backend)
+	assert.Equal(t, 1, open.Attempts)
+
+	// the tunnel is found down on the next status check
+	Status("table")
+	sessions, err := middleware.ListSessions(time.Now().Add(-time.Hour), "intra")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, model.SessionEndDropped, sessions[0].EndReason)
+}
+
+func TestSessionHistory_FailedConnect(t *testing.T) {
+	setupFakeVPN(t, "")
+	profile, _ := config.ResolveProfile("dev")
+
+	fake := &fakeBackend{state: model.StateDisconnected, connectErr: backend.ErrAuthFailed}
+	assert.Error(t, connectWithRetries(fake, testCredential(), profile))
+
+	sessions, err := middleware.ListSessions(time.Now().Add(-time.Hour), "")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, model.SessionEndConnectFailed, sessions[0].EndReason)
+	assert.Equal(t, model.FailureAuth, sessions[0].ErrorClass)
+	assert.NotNil(t, sessions[0].EndedAt)
+}
+
+func TestPrintSessions(t *testing.T) {
+	now := time.Date(2025, 6, 19, 12, 0, 0, 0, time.UTC)
+	ended := now.Add(-time.Hour)
+	sessions := []model.Session{
+		{ID: 2, Profile: "dev", Backend: "cisco", StartedAt: now.Add(-30 * time.Minute), Attempts: 1, ClientIP: "10.1.2.3"},
+		{ID: 1, Profile: "intra", Backend: "cisco", StartedAt: now.Add(-3 * time.Hour), EndedAt: &ended,
+			EndReason: model.SessionEndDisconnected, Attempts: 2, RxBytes: 10, TxBytes: 20},
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, printSessions(&out, sessions, "csv", now))
+	assert.Contains(t, out.String(), "id,profile,backend,started_at,ended_at,duration_seconds")
+	assert.Contains(t, out.String(), "1,intra,cisco,2025-06-19T09:00:00Z,2025-06-19T11:00:00Z,7200,disconnected,2,,,10,20")
+
+	out.Reset()
+	assert.NoError(t, printSessions(&out, sessions, "json", now))
+	assert.Contains(t, out.String(), `"client_ip": "10.1.2.3"`)
+
+	out.Reset()
+	assert.NoError(t, printSessions(&out, sessions, "table", now))
+	assert.Contains(t, out.String(), "active")
+
+	assert.Error(t, printSessions(&out, sessions, "xml", now))
+}
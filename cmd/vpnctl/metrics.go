@@ -0,0 +1,218 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package vpnctl
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/backend"
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+// sessionDurationBuckets are the upper bounds (seconds) of the session duration
+// histogram, from a minute to a day.
+var sessionDurationBuckets = []float64{60, 300, 900, 1800, 3600, 7200, 14400, 28800, 86400}
+
+// tunnelMetrics holds the Prometheus metrics served by the daemon. They are fed
+// by the events that are written to the session history (connects, session ends,
+// daemon reconnects and probes), so the dashboards and `vpnctl history` agree.
+type tunnelMetrics struct {
+	mu         sync.Mutex
+	connected  map[string]bool    // profile -> tunnel up
+	attempts   map[string]float64 // result (success, failure) -> connect attempts
+	buckets    []float64          // session duration histogram, cumulative per bucket
+	count      float64
+	sum        float64
+	reconnects float64
+	probes     map[[2]string]float64 // {profile, probe} -> latency of the last run in seconds
+}
+
+// metrics is fed by the connect, session and probe events of this process.
+var metrics = newTunnelMetrics()
+
+func init() {
+	backend.ProbeObserver = func(profile string, result model.ProbeResult) {
+		metrics.observeProbe(profile, result.Name, result.Latency)
+	}
+}
+
+func newTunnelMetrics() *tunnelMetrics {
+	return &tunnelMetrics{
+		connected: map[string]bool{},
+		attempts:  map[string]float64{},
+		buckets:   make([]float64, len(sessionDurationBuckets)),
+		probes:    map[[2]string]float64{},
+	}
+}
+
+// observeConnect records the connect attempts of one connect run; every attempt
+// before the last one failed.
+func (m *tunnelMetrics) observeConnect(profile string, attempts int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.attempts["failure"] += float64(attempts)
+		return
+	}
+	m.attempts["success"]++
+	m.attempts["failure"] += float64(max(attempts-1, 0))
+	m.setConnected(profile)
+}
+
+// observeSessionEnd records the duration of a session that just ended.
+func (m *tunnelMetrics) observeSessionEnd(s model.Session, endedAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seconds := endedAt.Sub(s.StartedAt).Seconds()
+	for i, bound := range sessionDurationBuckets {
+		if seconds <= bound {
+			m.buckets[i]++
+		}
+	}
+	m.count++
+	m.sum += seconds
+	m.connected[s.Profile] = false
+}
+
+// observeReconnect counts a reconnect started by the daemon.
+func (m *tunnelMetrics) observeReconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects++
+}
+
+// observeProbe records the latency of a post-connect probe.
+func (m *tunnelMetrics) observeProbe(profile, probe string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.probes[[2]string{profile, probe}] = latency.Seconds()
+}
+
+// markConnected marks the profile as the connected one, e.g. for the session
+// that is already open when the daemon starts.
+func (m *tunnelMetrics) markConnected(profile string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setConnected(profile)
+}
+
+func (m *tunnelMetrics) setConnected(profile string) {
+	for name := range m.connected {
+		m.connected[name] = false
+	}
+	m.connected[profile] = true
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format.
+func (m *tunnelMetrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	header(w, "vpnctl_connected", "gauge", "Whether the tunnel of the profile is up (1) or down (0).")
+	profiles := []string{}
+	for _, p := range config.VPN_PROFILES {
+		profiles = append(profiles, p.Name)
+	}
+	for name := range m.connected {
+		if !slices.Contains(profiles, name) {
+			profiles = append(profiles, name)
+		}
+	}
+	slices.Sort(profiles)
+	for _, name := range profiles {
+		value := 0.0
+		if m.connected[name] {
+			value = 1
+		}
+		sample(w, "vpnctl_connected", value, "profile", name)
+	}
+
+	header(w, "vpnctl_connect_attempts_total", "counter", "Connect attempts by result.")
+	for _, result := range []string{"success", "failure"} {
+		sample(w, "vpnctl_connect_attempts_total", m.attempts[result], "result", result)
+	}
+
+	header(w, "vpnctl_session_duration_seconds", "histogram", "Duration of ended VPN sessions.")
+	for i, bound := range sessionDurationBuckets {
+		sample(w, "vpnctl_session_duration_seconds_bucket", m.buckets[i], "le", formatFloat(bound))
+	}
+	sample(w, "vpnctl_session_duration_seconds_bucket", m.count, "le", "+Inf")
+	sample(w, "vpnctl_session_duration_seconds_sum", m.sum)
+	sample(w, "vpnctl_session_duration_seconds_count", m.count)
+
+	header(w, "vpnctl_reconnects_total", "counter", "Reconnects started by the daemon.")
+	sample(w, "vpnctl_reconnects_total", m.reconnects)
+
+	header(w, "vpnctl_probe_latency_seconds", "gauge", "Latency of the last run of each post-connect probe.")
+	keys := make([][2]string, 0, len(m.probes))
+	for key := range m.probes {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b [2]string) int {
+		return strings.Compare(a[0]+"\x00"+a[1], b[0]+"\x00"+b[1])
+	})
+	for _, key := range keys {
+		sample(w, "vpnctl_probe_latency_seconds", m.probes[key], "profile", key[0], "probe", key[1])
+	}
+}
+
+func header(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one sample line; labels are name/value pairs.
+func sample(w io.Writer, name string, value float64, labels ...string) {
+	if len(labels) > 0 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1])))
+		}
+		name += "{" + strings.Join(pairs, ",") + "}"
+	}
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(value))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// listenMetrics listens on the [daemon] metrics_address. Only loopback addresses
+// are accepted: the endpoint has no authentication.
+func listenMetrics(address string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid [daemon] metrics_address %q: %w", address, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("[daemon] metrics_address %q must be a loopback address such as 127.0.0.1:9477", address)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("listening on metrics address %s: %w", address, err)
+	}
+	return listener, nil
+}
+
+// metricsServer serves GET /metrics.
+func metricsServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.writeTo(w)
+	})
+	return &http.Server{Handler: mux}
+}
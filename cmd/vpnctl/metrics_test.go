@@ -0,0 +1,94 @@
+package vpnctl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// resetMetrics gives the test fresh metrics.
+func resetMetrics(t *testing.T) {
+	t.Helper()
+	orig := metrics
+	metrics = newTunnelMetrics()
+	t.Cleanup(func() { metrics = orig })
+}
+
+func scrape(m *tunnelMetrics) string {
+	var out strings.Builder
+	m.writeTo(&out)
+	return out.String()
+}
+
+func TestMetrics_Exposition(t *testing.T) {
+	m := newTunnelMetrics()
+	started := time.Date(2025, 6, 19, 2, 0, 0, 0, time.UTC)
+
+	m.observeConnect("dev", 3, nil)
+	m.observeConnect("intra", 2, errors.New("login failed"))
+	m.observeReconnect()
+	m.observeProbe("dev", "gitlab", 42*time.Millisecond)
+	out := scrape(m)
+
+	assert.Contains(t, out, "# TYPE vpnctl_connected gauge\n")
+	assert.Contains(t, out, "vpnctl_connected{profile=\"dev\"} 1\n")
+	assert.Contains(t, out, "vpnctl_connected{profile=\"intra\"} 0\n")
+	assert.Contains(t, out, "vpnctl_connect_attempts_total{result=\"success\"} 1\n")
+	assert.Contains(t, out, "vpnctl_connect_attempts_total{result=\"failure\"} 4\n")
+	assert.Contains(t, out, "vpnctl_reconnects_total 1\n")
+	assert.Contains(t, out, "vpnctl_probe_latency_seconds{profile=\"dev\",probe=\"gitlab\"} 0.042\n")
+
+	m.observeSessionEnd(model.Session{Profile: "dev", StartedAt: started}, started.Add(10*time.Minute))
+	out = scrape(m)
+	assert.Contains(t, out, "vpnctl_connected{profile=\"dev\"} 0\n")
+	assert.Contains(t, out, "vpnctl_session_duration_seconds_bucket{le=\"300\"} 0\n")
+	assert.Contains(t, out, "vpnctl_session_duration_seconds_bucket{le=\"900\"} 1\n")
+	assert.Contains(t, out, "vpnctl_session_duration_seconds_bucket{le=\"+Inf\"} 1\n")
+	assert.Contains(t, out, "vpnctl_session_duration_seconds_sum 600\n")
+	assert.Contains(t, out, "vpnctl_session_duration_seconds_count 1\n")
+}
+
+func TestMetrics_FedBySessionEvents(t *testing.T) {
+	setupFakeVPN(t, "")
+	resetMetrics(t)
+	profile, _ := config.ResolveProfile("dev")
+	fake := &fakeBackend{state: model.StateConnected}
+
+	recordSession(context.Background(), fake, profile, time.Now(), 1, nil)
+	assert.Contains(t, scrape(metrics), "vpnctl_connected{profile=\"dev\"} 1\n")
+
+	assert.NoError(t, disconnectWith(context.Background(), fake))
+	out := scrape(metrics)
+	assert.Contains(t, out, "vpnctl_connected{profile=\"dev\"} 0\n")
+	assert.Contains(t, out, "vpnctl_session_duration_seconds_count 1\n")
+}
+
+func TestMetrics_Endpoint(t *testing.T) {
+	resetMetrics(t)
+	metrics.observeReconnect()
+
+	_, err := listenMetrics("0.0.0.0:0")
+	assert.ErrorContains(t, err, "loopback")
+
+	listener, err := listenMetrics("127.0.0.1:0")
+	assert.NoError(t, err)
+	server := metricsServer()
+	go server.Serve(listener)
+	defer server.Close()
+
+	res, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.True(t, strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain"))
+	assert.Contains(t, string(body), "vpnctl_reconnects_total 1\n")
+}
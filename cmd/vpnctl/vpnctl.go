@@ -6,434 +6,422 @@
 package vpnctl
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"os/user"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/common-nighthawk/go-figure"
 	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/backend"
+	"github.com/goo-apps/vpnctl/internal/handler"
 	"github.com/goo-apps/vpnctl/internal/middleware"
 	"github.com/goo-apps/vpnctl/internal/model"
 	"github.com/goo-apps/vpnctl/logger"
+	"gopkg.in/yaml.v3"
 )
 
-var (
-	shouldRetryAgentLock    bool
-	shouldRetryConnectivity bool
+// Exit codes returned by Status so scripts can rely on them.
+const (
+	ExitConnected    = 0
+	ExitUsage        = 2
+	ExitDisconnected = 3
+	ExitUnknown      = 4
 )
 
-// Status checks the current VPN connection status using the Cisco Secure Client command line tool.
-// // It runs the command with a timeout to avoid hanging indefinitely.
-// // If the command times out, it logs an error and returns.
-// // If the command fails, it logs the error and prints the output.
-// // If the command succeeds, it logs the success and prints the output.
-// // This function is useful for checking if the VPN is currently connected or disconnected.
-func Status() {
+// Status checks the current VPN connection status through the backend of the last connected profile.
+// A reminder is added to the notices when the password of the connected profile expires soon.
+// It prints the parsed state as a table (default), json or yaml and returns the exit code:
+// 0 when connected, 3 when disconnected and 4 when the state is unknown or in transition.
+func Status(output string) int {
 	logger.Infof("Checking VPN status...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, config.VPN_BINARY_PATH, "status", "-s")
-	output, err := cmd.CombinedOutput()
-
-	if ctx.Err() == context.DeadlineExceeded {
-		logger.Errorf("status check: %v", fmt.Errorf("vpn status timed out"))
-		return
+	var state *model.VPNState
+	if c := dialDaemon(); c != nil {
+		resp, err := c.Status()
+		if err != nil {
+			logger.Errorf("retrieving VPN status from the vpnctl daemon: %v", err)
+		} else {
+			state = resp.State
+		}
+	} else {
+		var err error
+		if state, err = statusOf(context.Background(), currentBackend()); err != nil {
+			logger.Errorf("retrieving VPN status: %v", err)
+		}
 	}
-
-	if err != nil {
-		logger.Errorf("retrieving VPN status: %v", err)
-		fmt.Println(string(output))
-		return
+	if state == nil {
+		state = &model.VPNState{State: model.StateUnknown}
+	}
+	var profile *model.Profile
+	if state.Profile != "" {
+		profile, _ = config.ResolveProfile(state.Profile)
+	}
+	if reminder := handler.ExpiryReminder(profile, time.Now()); reminder != "" {
+		state.Notices = append(state.Notices, reminder)
 	}
 
-	logger.Infof("VPN status retrieved successfully")
-	fmt.Println(string(output))
-
+	if err := printState(os.Stdout, state, output); err != nil {
+		logger.Errorf("%v", err)
+		return ExitUsage
+	}
+	return StatusExitCode(state)
 }
 
-// func Status() {
-// 	cmd := exec.Command(vpnCmd, "status")
-// 	output, err := cmd.CombinedOutput()
-// 	if err != nil {
-// 		logger.Errorf("Error retrieving VPN status.")
-// 		return
-// 	}
-// 	logger.Infof(string(output))
-// }
-
-// Disconnect terminates the current VPN connection and kills the Cisco Secure Client GUI.
-// It runs the `vpn disconnect` command to disconnect the VPN.
-// After disconnecting, it attempts to kill the Cisco Secure Client GUI process using `pkill`.
-func DisconnectWithKillPid() {
-	logger.Infof("Attempting to disconnect VPN...")
-	exec.Command(config.VPN_BINARY_PATH, "disconnect").Run()
-	logger.Infof("VPN disconnected")
-
-	// Kill Cisco Secure Client UI only, not vpnagentd
-	exec.Command("pkill", "-x", "Cisco Secure Client").Run()
-	logger.Infof("Cisco Secure Client UI process killed")
-
-	pids, err := getPIDs("vpn")
-	if err != nil {
-		logger.Errorf("getting VPN PIDs: %v", err)
-		return
+// statusOf asks the backend for the tunnel state, names the last connected profile
+// while connected and closes the open session when the tunnel dropped.
+func statusOf(ctx context.Context, b backend.Backend) (*model.VPNState, error) {
+	state, err := b.Status(ctx)
+	if state == nil {
+		state = &model.VPNState{State: model.StateUnknown}
 	}
-
-	for _, pid := range pids {
-		// Use `ps -p <pid> -o command=` to get the full process path/command
-		out, err := exec.Command("ps", "-p", fmt.Sprintf("%d", pid), "-o", "command=").Output()
-		if err != nil {
-			logger.Errorf("could not inspect process %d: %v", pid, err)
-			continue
-		}
-
-		cmdline := string(out)
-		cmdline = strings.TrimSpace(cmdline)
-
-		if strings.Contains(cmdline, "vpnagentd") {
-			logger.Infof("Skipping vpnagentd process (PID %d)", pid)
-			continue
+	if state.State == model.StateConnected {
+		if last, err := middleware.GetLastConnectedProfile(); err == nil {
+			state.Profile = last
 		}
+	}
+	if state.State == model.StateDisconnected {
+		endDroppedSession()
+	}
+	return state, err
+}
 
-		logger.Infof("Killing VPN process with PID %d", pid)
+// StatusExitCode maps a VPN state onto the documented `vpnctl status` exit codes.
+func StatusExitCode(state *model.VPNState) int {
+	switch state.State {
+	case model.StateConnected:
+		return ExitConnected
+	case model.StateDisconnected:
+		return ExitDisconnected
+	default:
+		return ExitUnknown
+	}
+}
 
-		process, err := os.FindProcess(pid)
+// printState renders the state in the requested output format.
+func printState(w io.Writer, state *model.VPNState, output string) error {
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(state, "", "  ")
 		if err != nil {
-			logger.Errorf(fmt.Sprintf("finding process %d", pid), err)
-			continue
+			return fmt.Errorf("marshaling status to JSON: %w", err)
 		}
-
-		err = process.Signal(os.Interrupt)
+		fmt.Fprintln(w, string(data))
+	case "yaml":
+		data, err := yaml.Marshal(state)
 		if err != nil {
-			logger.Errorf(fmt.Sprintf("killing VPN process %d", pid), err)
+			return fmt.Errorf("marshaling status to YAML: %w", err)
+		}
+		fmt.Fprint(w, string(data))
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "State\t%s\n", state.State)
+		if state.Profile != "" {
+			fmt.Fprintf(tw, "Profile\t%s\n", state.Profile)
+		}
+		if state.Host != "" {
+			fmt.Fprintf(tw, "Host\t%s\n", state.Host)
+		}
+		if state.ClientAddress != "" {
+			fmt.Fprintf(tw, "Client address\t%s\n", state.ClientAddress)
+		}
+		if state.ConnectedSince != nil {
+			fmt.Fprintf(tw, "Connected since\t%s (%s)\n", state.ConnectedSince.Format(time.RFC3339),
+				time.Since(*state.ConnectedSince).Truncate(time.Second))
+		}
+		if state.LatestHandshake != nil {
+			fmt.Fprintf(tw, "Latest handshake\t%s ago\n", time.Since(*state.LatestHandshake).Truncate(time.Second))
+		}
+		if state.RxBytes > 0 || state.TxBytes > 0 {
+			fmt.Fprintf(tw, "Transfer\t%d B received, %d B sent\n", state.RxBytes, state.TxBytes)
 		}
+		for _, notice := range state.Notices {
+			fmt.Fprintf(tw, "Notice\t%s\n", notice)
+		}
+		tw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q, use json, yaml or table", output)
 	}
-
-	logger.Infof("VPN disconnected and related processes (excluding vpnagentd) killed")
+	return nil
 }
 
-func Disconnect() {
-	logger.Infof("Attempting to disconnect VPN...")
-	exec.Command(config.VPN_BINARY_PATH, "disconnect").Run()
-	logger.Infof("VPN disconnected")
-	exec.Command("pkill", "-x", "Cisco Secure Client").Run()
+// DisconnectWithKillPid terminates the current VPN connection, through the vpnctl
+// daemon when one is running.
+// For the Cisco backend it also kills the Cisco Secure Client GUI and every
+// remaining `vpn` process except vpnagentd.
+func DisconnectWithKillPid() {
+	if c := dialDaemon(); c != nil {
+		if _, err := c.Disconnect(); err != nil {
+			logger.Errorf("%v", err)
+			return
+		}
+		logger.Infof("VPN disconnected by the vpnctl daemon")
+		return
+	}
+	if err := disconnectWith(context.Background(), currentBackend()); err != nil {
+		logger.Errorf("%v", err)
+	}
 }
 
-// KillGUI kills the Cisco Secure Client GUI and optionally the VPN process
-// It uses `pkill` to find and terminate the GUI process by name.
-// It also retrieves the VPN process IDs using `pgrep` and sends an interrupt signal to them.
-// This function is useful for cleaning up the GUI and VPN processes when they are no longer needed.
-func KillGUI() {
-	logger.Infof("Killing Cisco Secure Client GUI...")
-	exec.Command("pkill", "-x", "Cisco Secure Client").Run()
-	logger.Infof("Cisco GUI killed")
-	// Optionally, you can also kill the VPN process
-	pids, err := getPIDs("vpn")
-	if err != nil {
-		logger.Errorf("getting VPN PIDs: %v", err)
-		return
+// disconnectWith disconnects the backend and ends the open session.
+func disconnectWith(ctx context.Context, b backend.Backend) error {
+	// the counters are only available while the tunnel is still up
+	state, _ := b.Status(ctx)
+	if state == nil {
+		state = &model.VPNState{}
 	}
-	for _, pid := range pids {
-		process, err := os.FindProcess(pid)
-		if err != nil {
-			logger.Errorf(fmt.Sprintf("finding process %d", pid), err)
-			continue
+
+	if cisco, ok := b.(*backend.Cisco); ok {
+		if err := cisco.ForceDisconnect(ctx); err != nil {
+			logger.Errorf("%v", err)
 		}
-		logger.Infof(fmt.Sprintf("Killing VPN process with PID %d", pid))
-		err = process.Signal(os.Interrupt) // Use SIGINT to gracefully stop
-		if err != nil {
-			logger.Errorf(fmt.Sprintf("killing VPN process %d", pid), err)
+	} else {
+		if err := b.Disconnect(ctx); err != nil {
+			return err
 		}
+		logger.Infof("VPN disconnected")
 	}
-	logger.Infof("VPN processes killed")
+	return endSessions(model.SessionEndDisconnected, state.RxBytes, state.TxBytes)
 }
 
-// getPIDs retrieves process IDs for a given process name using pgrep
-// It runs the `pgrep -f` command to find all processes matching the name.
-// It returns a slice of integers representing the process IDs.
-// If an error occurs during execution, it returns an error.
-// This function is useful for finding and managing processes by name.
-func getPIDs(name string) ([]int, error) {
-	out, err := exec.Command("pgrep", "-f", name).Output()
-	if err != nil {
-		return nil, err
+// errNoGUI is returned for backends without a desktop client.
+var errNoGUI = errors.New("the current VPN backend has no GUI")
+
+// KillGUI kills the desktop client of the current backend, if it has one,
+// through the vpnctl daemon when one is running.
+// For Cisco it also interrupts the `vpn` processes.
+func KillGUI() {
+	var err error
+	if c := dialDaemon(); c != nil {
+		_, err = c.Kill()
+	} else {
+		err = killGUIOf(currentBackend())
+	}
+	switch {
+	case err == nil:
+	case err.Error() == errNoGUI.Error(): // also when relayed by the daemon
+		logger.Warningf("%v", err)
+	default:
+		logger.Errorf("%v", err)
 	}
+}
 
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	var pids []int
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		pid, err := strconv.Atoi(line)
-		if err != nil {
-			continue
-		}
-		pids = append(pids, pid)
+func killGUIOf(b backend.Backend) error {
+	g, ok := b.(backend.GUI)
+	if !ok {
+		return errNoGUI
 	}
-	return pids, nil
+	return g.KillGUI()
 }
 
-// LaunchGUI starts the Cisco Secure Client GUI application.
-// It uses the `open` command to launch the GUI application.
-// If an error occurs while launching the GUI, it logs the error.
+// LaunchGUI starts the desktop client of the current backend, if it has one.
 // This function is useful for starting the GUI after a successful VPN connection.
 func LaunchGUI() {
-	logger.Infof("Launching Cisco Secure Client GUI...")
-	err := exec.Command("open", config.VPN_GUI_PATH).Run()
-	if err != nil {
-		logger.Errorf("launching Cisco Secure Client GUI: %v", err)
+	g, ok := currentBackend().(backend.GUI)
+	if !ok {
+		logger.Warningf("the current VPN backend has no GUI")
+		return
+	}
+	if err := g.LaunchGUI(); err != nil {
+		logger.Errorf("%v", err)
 	}
-	logger.Infof("Cisco GUI launched")
 }
 
-func KillCiscoProcesses() error {
-	processes := []string{"Cisco Secure Client"} // Do NOT include vpnagentd
-	var killErrors []string
-
-	for _, name := range processes {
-		cmd := exec.Command("pgrep", "-f", name)
-		output, err := cmd.Output()
-		if err != nil {
-			logger.Warningf(fmt.Sprintf("failed to find process: %s", name), err)
-			continue
-		}
-
-		pids := strings.Fields(string(output))
-		for _, pid := range pids {
-			killCmd := exec.Command("kill", "-9", pid)
-			if err := killCmd.Run(); err != nil {
-				msg := fmt.Sprintf("failed to kill PID %v for %v: %v", pid, name, err)
-				logger.Errorf(msg, err)
-				killErrors = append(killErrors, msg)
-			} else {
-				logger.Infof(fmt.Sprintf("Killed %v (PID %v)", name, pid))
+// currentBackend returns the backend of the last connected profile,
+// falling back to the default backend when there is no history yet.
+func currentBackend() backend.Backend {
+	if last, err := middleware.GetLastConnectedProfile(); err == nil {
+		if profile, err := config.ResolveProfile(last); err == nil {
+			if b, err := backend.ForProfile(profile); err == nil {
+				return b
 			}
 		}
 	}
-
-	time.Sleep(2 * time.Second)
-
-	if len(killErrors) > 0 {
-		return fmt.Errorf("not all processes could be killed:\n%v", strings.Join(killErrors, "\n"))
-	}
-	return nil
+	b, _ := backend.Get(backend.DefaultBackend)
+	return b
 }
 
 // Connect establishes a VPN connection using the specified profile.
-// It reads the VPN profile script from a hidden path, replaces placeholders with credentials,
-// and executes the VPN command with the provided script.
+// The profile name (or alias) is resolved against the [[profile]] tables in the configuration,
+// and the connection is made through the backend the profile selects.
 // It checks the current VPN connection status before attempting to connect.
-// If the VPN is already connected, it aborts the connection operation.
-// It also reads the credentials for the specified profile from a hidden file.
-func Connect(credential *model.CREDENTIAL_FOR_LOGIN, profile string) {
-	connectWithRetries(credential, profile, config.VPN_CONNECTION_RETRY_COUNT)
+// If the VPN is already connected, it aborts the connection operation. Once connected
+// in-process, the backend's desktop client is opened.
+// Unknown profile names fail with the list of configured profiles.
+// While the vpnctl daemon runs, the connect is handed to it so there is a single
+// connection owner; the daemon logs in with the credential from its own store.
+// Otherwise the stored credential is used, or asked for when there is none.
+func Connect(name string) error {
+	profile, err := config.ResolveProfile(name)
+	if err != nil {
+		return err
+	}
+	b, err := backend.ForProfile(profile)
+	if err != nil {
+		return err
+	}
+	if c := dialDaemon(); c != nil {
+		if b.Capabilities().Credentials {
+			remindExpiry(profile)
+		}
+		logger.Infof("Handing the connect for profile %v to the vpnctl daemon", profile.Name)
+		_, err := c.Connect(profile.Name)
+		return err
+	}
+	credential := &model.CREDENTIAL_FOR_LOGIN{}
+	if b.Capabilities().Credentials {
+		if credential, err = promptCredential(profile); err != nil {
+			return fmt.Errorf("failed to get credentials: %w", err)
+		}
+		remindExpiry(profile)
+	}
+	if err := connectWithRetries(b, credential, profile); err != nil {
+		return err
+	}
+	// only here: the daemon reconnects in the background and opens no windows
+	if g, ok := b.(backend.GUI); ok && b.Capabilities().GUI {
+		if err := g.LaunchGUI(); err != nil {
+			logger.Errorf("%v", err)
+		}
+	}
+	return nil
 }
 
-// connectWithRetries attempts to connect to the VPN with retries.
-// It checks the current VPN connection status, reads the profile script, and executes the VPN command.
-// If the VPN is already connected to a different profile, it disconnects first.
-// It retries the connection if it detects a Cisco VPN agent lock.
-// It uses a recursive approach to retry the connection up to a maximum number of retries.
-// This function is useful for establishing a VPN connection with error handling and retry logic.
-// It reads the credentials for the specified profile from a hidden file.
-// It also handles the case where the VPN is already connected to a different profile.
-func connectWithRetries(credential *model.CREDENTIAL_FOR_LOGIN, profile string, retryCount int) { // Removed 'credential *model.CREDENTIAL_FOR_LOGIN'
-	logger.Infof(fmt.Sprintf("Initiating VPN connection using profile: %v", profile))
-
-	profilePath := getProfilePath(profile)
-	if profilePath == "" {
-		logger.Infof("Unknown VPN profile: %v", profile)
-		return
+// remindExpiry warns when the password the profile uses expires soon.
+func remindExpiry(profile *model.Profile) {
+	if reminder := handler.ExpiryReminder(profile, time.Now()); reminder != "" {
+		logger.Warningf("%s", reminder)
 	}
+}
 
-	// implement keychain here and get rid of credential files
-	// username, password, y_flag, secondPassword, err := readCredentials(profile)
-	// if err != nil {
-	// 	logger.Fatalf("reading credentials for profile %v: %v", profile, err)
-	// 	return
-	// }
+// promptCredential returns the stored credential of a profile, asking for one
+// when none is stored; replaced in tests.
+var promptCredential = handler.GetOrPromptCredential
 
-	logger.Infof("Checking current VPN connection status...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// connectWithRetries connects to the VPN following the profile's retry policy.
+// It checks the current VPN connection status and asks the backend to connect.
+// If the VPN is already connected to a different profile, it disconnects first.
+// Failed attempts are classified (agent lock, auth failure, network unreachable,
+// server busy, timeout) and retried with backoff, retried after restarting the
+// agent, or aborted, as configured in [profile.retry].
+func connectWithRetries(b backend.Backend, credential *model.CREDENTIAL_FOR_LOGIN, profile *model.Profile) error {
+	logger.Infof(fmt.Sprintf("Initiating VPN connection using profile: %v (backend: %v)", profile.Name, b.Name()))
 
-	cmd := exec.CommandContext(ctx, config.VPN_BINARY_PATH, "status")
-	output, _ := cmd.CombinedOutput()
+	logger.Infof("Checking current VPN connection status...")
+	ctx := context.Background()
 
-	if contains(string(output), "Connected") {
+	state, err := b.Status(ctx)
+	if err == nil && state.State == model.StateDisconnected {
+		endDroppedSession()
+	}
+	if err == nil && state.State == model.StateConnected {
 		last, err := middleware.GetLastConnectedProfile()
 		if err != nil {
 			logger.Warningf("retrieve error: %v", err)
 		}
 		logger.Infof("Last connected VPN profile: %v", last)
 
-		if last == profile {
-			logger.Infof(fmt.Sprintf("VPN already connected to profile: %v. Aborting connect operation.", profile))
-			return
+		if last == profile.Name {
+			logger.Infof(fmt.Sprintf("VPN already connected to profile: %v. Aborting connect operation.", profile.Name))
+			return nil
+		}
+		logger.Infof(fmt.Sprintf("VPN connected to profile %v, switching to %v...", last, profile.Name))
+		if err := b.Disconnect(ctx); err != nil {
+			logger.Errorf("%v", err)
+		}
+		if err := endSessions(model.SessionEndSwitched, state.RxBytes, state.TxBytes); err != nil {
+			logger.Errorf("%v", err)
 		}
-		logger.Infof(fmt.Sprintf("VPN connected to profile %v, switching to %v...", last, profile))
-		Disconnect()
-	}
-
-	if err := KillCiscoProcesses(); err != nil {
-		logger.Errorf("failed to kill Cisco processes before reconnect: %v", err)
-	}
-
-	// Deprecated: Reading profile script from file ---
-	// logger.Infof("reading VPN profile script from (hidden path)")
-	// scriptContent, err := os.ReadFile(profilePath)
-	// if err != nil {
-	// logger.Errorf("reading VPN profile: %v", err)
-	// return
-	// }
-
-	// username
-	// password
-	// Y/N for second factor prompt
-	// second_password (if applicable)
-	var scriptBuilder strings.Builder
-	scriptBuilder.WriteString(credential.Username + "\n")
-	scriptBuilder.WriteString(credential.Password + "\n")
-	scriptBuilder.WriteString(credential.YFlag + "\n")
-	if profile == "dev" {
-		scriptBuilder.WriteString(credential.Push + "\n")
-	}
-	script := scriptBuilder.String()
-
-	// Create a temporary file for the script input
-	tempFile, err := os.CreateTemp("", "vpn_input_*.txt")
-	if err != nil {
-		logger.Errorf("creating temp VPN input file: %v", err)
-		return
 	}
-	tempScript := tempFile.Name()
 
-	_, err = tempFile.WriteString(script)
+	started := time.Now()
+	attempts, err := backend.ConnectWithRetry(ctx, b, profile, credential)
+	recordSession(ctx, b, profile, started, attempts, err)
 	if err != nil {
-		tempFile.Close()
-		os.Remove(tempScript)
-		logger.Errorf("writing to temp VPN input file: %v", err)
-		return
+		if errors.Is(err, backend.ErrAgentLocked) {
+			logger.Infof("The Cisco VPN agent is still locked after automatic recovery. Please restart Cisco Secure Client (AnyConnect) manually and try again.")
+		}
+		return fmt.Errorf("connecting to profile %v: %w", profile.Name, err)
 	}
-	tempFile.Close()
-
-	defer os.Remove(tempScript)
-
-	logger.Infof("Running VPN command with provided script")
 
-	vpnProfile := ""
-	switch profile {
-	case "dev":
-		vpnProfile = "DEV-VPN-REMOTE"
-	case "intra":
-		vpnProfile = "INTRA"
+	if err := middleware.SetLastConnectedProfile(profile.Name); err != nil {
+		logger.Errorf("store error: %v", err)
 	}
+	return nil
+}
 
-	cmd = exec.Command(config.VPN_BINARY_PATH, "connect", vpnProfile, "-s")
-
-	stdinFile, err := os.Open(tempScript)
-	if err != nil {
-		logger.Errorf("opening temp script: %v", err)
-		return
+// recordSession writes the outcome of a connect to the session history: an active
+// session after a successful connect, or a closed connect_failed one with the
+// failure class otherwise.
+func recordSession(ctx context.Context, b backend.Backend, profile *model.Profile, started time.Time, attempts int, connectErr error) {
+	session := model.Session{
+		Profile:   profile.Name,
+		Backend:   b.Name(),
+		StartedAt: started,
+		Attempts:  attempts,
 	}
-	defer stdinFile.Close()
-	defer os.Remove(tempScript)
-
-	cmd.Stdin = stdinFile
-
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		logger.Errorf("getting stdout pipe: %s", err)
-		return
+	if connectErr != nil {
+		now := time.Now()
+		session.EndedAt = &now
+		session.EndReason = model.SessionEndConnectFailed
+		session.ErrorClass = backend.Classify(connectErr)
+	} else {
+		session.StartedAt = time.Now()
+		if state, err := b.Status(ctx); err == nil {
+			session.ClientIP = state.ClientAddress
+		}
 	}
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		logger.Errorf("getting stderr pipe: %v", err)
-		return
+	if _, err := middleware.StartSession(session); err != nil {
+		logger.Errorf("%v", err)
 	}
+	metrics.observeConnect(profile.Name, attempts, connectErr)
+}
 
-	if err := cmd.Start(); err != nil {
-		logger.Errorf("starting VPN command: %v", err)
+// endDroppedSession closes a session that is still open although the tunnel is down,
+// i.e. the VPN dropped without vpnctl disconnecting it.
+func endDroppedSession() {
+	open, err := middleware.GetOpenSession()
+	if err != nil {
 		return
 	}
-
-	stdoutLines := make(chan string, 100)
-	done := make(chan struct{})
-
-	go func() {
-		scanner := bufio.NewScanner(stdoutPipe)
-		for scanner.Scan() {
-			line := scanner.Text()
-			fmt.Println("[VPN stdout] " + line)
-			stdoutLines <- line
-		}
-		close(done)
-	}()
-
-	go func() {
-		scanner := bufio.NewScanner(stderrPipe)
-		for scanner.Scan() {
-			logger.Errorf("VPN stderr: %v", errors.New(scanner.Text()))
-		}
-	}()
-
-	if err := cmd.Wait(); err != nil {
-		logger.Errorf("VPN command exited with error: %v", err)
+	logger.Warningf("VPN session for profile %v (started %v) dropped", open.Profile, open.StartedAt.Local().Format(time.RFC3339))
+	if err := endSessions(model.SessionEndDropped, 0, 0); err != nil {
+		logger.Errorf("%v", err)
 	}
+}
 
-	<-done
-	close(stdoutLines)
-
-	shouldRetry := false
-	for line := range stdoutLines {
-		if strings.Contains(line, "Connect capability is unavailable") {
-			logger.Infof("Detected Cisco VPN agent lock. Please manually restart Cisco Secure Client (AnyConnect) and try again.")
-			shouldRetry = false
-			break
-		}
+// endSessions ends the open session with the given reason and byte counters and
+// records its duration in the metrics.
+func endSessions(reason string, rxBytes, txBytes int64) error {
+	now := time.Now()
+	open, _ := middleware.GetOpenSession()
+	if err := middleware.EndOpenSessions(reason, now, rxBytes, txBytes); err != nil {
+		return err
 	}
-
-	if shouldRetry && retryCount < config.VPN_CONNECTION_RETRY_COUNT {
-		time.Sleep(2 * time.Second)
-		logger.Infof(fmt.Sprintf("Retrying VPN connection to profile: %v (attempt %d)", profile, retryCount+1))
-		connectWithRetries(credential, profile, retryCount+1)
-		return
+	if open != nil {
+		metrics.observeSessionEnd(*open, now)
 	}
-
-	go func() {
-		if err := middleware.SetLastConnectedProfile(profile); err != nil {
-			logger.Errorf("store error: %v", err)
-		}
-	}()
-
-	LaunchGUI()
+	return nil
 }
 
 // getProfilePath returns the file path for the specified VPN profile.
-// It constructs the path based on the user's home directory and the profile name.
-// The function supports "intra" and "dev" profiles, returning the appropriate credential file path.
-// If the profile name is not recognized, it returns an empty string.
+// It constructs the path based on the user's home directory and the configured profile name,
+// so aliases resolve to the same credential file as the profile they belong to.
+// If the profile name is not configured, it returns an empty string.
 // This function is useful for locating the VPN profile scripts needed for connection.
 func getProfilePath(name string) string {
-	u, _ := user.Current()
-	switch name {
-	case "intra":
-		return filepath.Join(u.HomeDir, ".vpnctl", ".credential", ".credential_intra")
-	case "dev":
-		return filepath.Join(u.HomeDir, ".vpnctl", ".credential", ".credential_dev")
-	default:
+	profile, err := config.ResolveProfile(name)
+	if err != nil {
 		return ""
 	}
+	u, _ := user.Current()
+	return filepath.Join(u.HomeDir, ".vpnctl", ".credential", ".credential_"+profile.Name)
 }
 
 // contains checks if the given text contains the specified keyword.
@@ -446,38 +434,32 @@ func contains(text, keyword string) bool {
 
 // readCredentials reads the credentials for the specified VPN profile from a hidden file.
 // It constructs the file path based on the user's home directory and the profile name.
-// It supports "intra" and "dev" profiles, returning the appropriate credentials.
+// Any configured profile is supported; profiles with push MFA need a fourth line for the second password.
 // The function reads the file, splits it into lines, and returns the credentials as strings.
 // If the file cannot be read or the format is invalid, it returns an error.
 // This function is useful for securely retrieving the VPN credentials needed for connection.
 // Deprecated: This function is depricated as migrated the credentialk management to keyring
-func readCredentials(profile string) (string, string, string, string, error) {
-	basePath := filepath.Join(os.Getenv("HOME"), ".vpnctl", ".credential")
-	var credentialsPath string
-
-	switch profile {
-	case "intra":
-		credentialsPath = filepath.Join(basePath, ".credential_intra")
-	case "dev":
-		credentialsPath = filepath.Join(basePath, ".credential_dev")
-	default:
-		return "", "", "", "", fmt.Errorf("unsupported profile for credentials")
+func readCredentials(name string) (string, string, string, string, error) {
+	profile, err := config.ResolveProfile(name)
+	if err != nil {
+		return "", "", "", "", fmt.Errorf("unsupported profile for credentials: %w", err)
 	}
+	credentialsPath := filepath.Join(os.Getenv("HOME"), ".vpnctl", ".credential", ".credential_"+profile.Name)
 
-	logger.Infof(fmt.Sprintf("Reading credentials for %v (hidden path)", profile))
+	logger.Infof(fmt.Sprintf("Reading credentials for %v (hidden path)", profile.Name))
 	creds, err := os.ReadFile(credentialsPath)
 	if err != nil {
 		return "", "", "", "", err
 	}
 
 	lines := strings.Split(strings.TrimSpace(string(creds)), "\n")
-	if profile == "intra" && len(lines) >= 3 {
+	if profile.MFA != "push" && len(lines) >= 3 {
 		return strings.TrimSpace(lines[0]), strings.TrimSpace(lines[1]), strings.TrimSpace(lines[2]), "", nil
-	} else if profile == "dev" && len(lines) >= 4 {
+	} else if profile.MFA == "push" && len(lines) >= 4 {
 		return strings.TrimSpace(lines[0]), strings.TrimSpace(lines[1]), strings.TrimSpace(lines[2]), strings.TrimSpace(lines[3]), nil
 	}
 
-	return "", "", "", "", fmt.Errorf("invalid credentials format for profile: %v", profile)
+	return "", "", "", "", fmt.Errorf("invalid credentials format for profile: %v", profile.Name)
 }
 
 // Info prints the information about the vpnctl CLI tool.
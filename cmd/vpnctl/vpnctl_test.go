@@ -2,14 +2,18 @@ package vpnctl
 
 import (
 	"bytes"
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/user"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/backend"
 	"github.com/goo-apps/vpnctl/internal/middleware"
 	"github.com/goo-apps/vpnctl/internal/model"
 	"github.com/goo-apps/vpnctl/logger"
@@ -36,6 +40,11 @@ func TestMain(m *testing.M) {
     infof = mockInfof
     errorf = mockErrorf
     warningf = mockWarningf
+    logger.InitLogger(false, "")
+    config.SystemConfigPath, config.UserConfigPath = "", "" // only the embedded configuration
+    if err := config.LoadAllConfigAtOnce(""); err != nil {
+        panic(err)
+    }
     os.Exit(m.Run())
 }
 
@@ -148,21 +157,75 @@ func patchUserCurrent() func() {
 	return func() { userCurrentFunc = orig }
 }
 
-// Patch KillCiscoProcesses, Disconnect, LaunchGUI
-var (
-	killCiscoProcessesFunc = KillCiscoProcesses
-	disconnectFunc         = Disconnect
-	launchGUIFunc          = LaunchGUI
-)
+// Patch LaunchGUI
+var launchGUIFunc = LaunchGUI
 
 func patchHelpers() func() {
-	origKill := killCiscoProcessesFunc
-	origDisc := disconnectFunc
 	origLaunch := launchGUIFunc
-	return func() {
-		killCiscoProcessesFunc = origKill
-		disconnectFunc = origDisc
-		launchGUIFunc = origLaunch
+	return func() { launchGUIFunc = origLaunch }
+}
+
+// fakeVPNScript stands in for the Cisco `vpn` CLI. It records every invocation,
+// answers `status` with $FAKE_VPN_STATUS and, on `connect`, asks the '|' separated
+// prompts in $FAKE_VPN_PROMPTS and stores the answers it reads from stdin.
+const fakeVPNScript = `#!/bin/sh
+echo "$*" >> "$FAKE_VPN_LOG"
+case "$1" in
+status) printf '%s\n' "$FAKE_VPN_STATUS" ;;
+connect)
+  : > "$FAKE_VPN_STDIN"
+  IFS='|'
+  for p in $FAKE_VPN_PROMPTS; do printf '%s' "$p"; read -r ans; echo "$ans" >> "$FAKE_VPN_STDIN"; done
+  echo; echo "state: Connected" ;;
+esac
+`
+
+type fakeVPN struct {
+	log   string
+	stdin string
+}
+
+// setupFakeVPN points the package at a fake vpn binary and a throwaway database.
+func setupFakeVPN(t *testing.T, status string) *fakeVPN {
+	t.Helper()
+	dir := t.TempDir()
+
+	bin := filepath.Join(dir, "vpn")
+	assert.NoError(t, os.WriteFile(bin, []byte(fakeVPNScript), 0755))
+
+	f := &fakeVPN{log: filepath.Join(dir, "calls.log"), stdin: filepath.Join(dir, "stdin.txt")}
+	t.Setenv("FAKE_VPN_LOG", f.log)
+	t.Setenv("FAKE_VPN_STDIN", f.stdin)
+	t.Setenv("FAKE_VPN_STATUS", status)
+	t.Setenv("FAKE_VPN_PROMPTS", "Username: |Password: |accept? [y/n]: ")
+
+	origBinary, origDB, origGUI, origSocket := config.VPN_BINARY_PATH, config.SQLITE_DB_PATH, config.VPN_GUI_PATH, config.DAEMON_SOCKET
+	config.VPN_BINARY_PATH = bin
+	config.SQLITE_DB_PATH = filepath.Join(dir, "vpnctl.db")
+	config.VPN_GUI_PATH = filepath.Join(dir, "no-gui")
+	config.DAEMON_SOCKET = filepath.Join(dir, "no-daemon.sock")
+	t.Cleanup(func() {
+		config.VPN_BINARY_PATH, config.SQLITE_DB_PATH, config.VPN_GUI_PATH, config.DAEMON_SOCKET = origBinary, origDB, origGUI, origSocket
+	})
+	return f
+}
+
+func (f *fakeVPN) calls() string {
+	data, _ := os.ReadFile(f.log)
+	return string(data)
+}
+
+func (f *fakeVPN) answers() string {
+	data, _ := os.ReadFile(f.stdin)
+	return string(data)
+}
+
+func testCredential() *model.CREDENTIAL_FOR_LOGIN {
+	return &model.CREDENTIAL_FOR_LOGIN{
+		Username: "user",
+		Password: "pass",
+		YFlag:    "yflag",
+		Push:     "push",
 	}
 }
 
@@ -185,17 +248,13 @@ func TestContains(t *testing.T) {
 }
 
 func TestReadCredentials(t *testing.T) {
-	restore := patchFileOps()
-	defer restore()
-	readFileFunc = func(path string) ([]byte, error) {
-		if strings.Contains(path, "intra") {
-			return []byte("user\npass\nyflag\n"), nil
-		}
-		if strings.Contains(path, "dev") {
-			return []byte("user\npass\nyflag\npush\n"), nil
-		}
-		return nil, errors.New("not found")
-	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".vpnctl", ".credential")
+	assert.NoError(t, os.MkdirAll(dir, 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".credential_intra"), []byte("user\npass\nyflag\n"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".credential_dev"), []byte("user\npass\nyflag\npush\n"), 0600))
+
 	u, p, y, s, err := readCredentials("intra")
 	assert.NoError(t, err)
 	assert.Equal(t, "user", u)
@@ -215,127 +274,167 @@ func TestReadCredentials(t *testing.T) {
 }
 
 func TestConnectWithRetries_ProfileNotFound(t *testing.T) {
-	resetLogger()
-	connectWithRetries(&model.CREDENTIAL_FOR_LOGIN{}, "unknown", 0)
-	assert.Contains(t, logInfoMsgs[len(logInfoMsgs)-1], "Unknown VPN profile")
+	err := Connect("unknown")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown VPN profile")
+	assert.Contains(t, err.Error(), "intra, dev")
 }
 
 func TestConnectWithRetries_AlreadyConnectedSameProfile(t *testing.T) {
-	resetLogger()
-	restore := patchHelpers()
-	defer restore()
-	killCiscoProcessesFunc = func() error { return nil }
-	disconnectFunc = func() {}
-	launchGUIFunc = func() {}
+	f := setupFakeVPN(t, "state: Connected")
+	profile, _ := config.ResolveProfile("dev")
+	setLastConnected(t, "dev")
 
-	mockLastProfile = "dev"
-	mockGetProfileErr = nil
+	assert.NoError(t, connectWithRetries(ciscoBackend(t), testCredential(), profile))
+	assert.NotContains(t, f.calls(), "connect DEV-VPN-REMOTE")
+	assert.NotContains(t, f.calls(), "disconnect")
+}
 
-	// Patch exec.CommandContext to simulate "Connected"
-	execCommandContextFunc = func(ctx interface{}, name string, arg ...string) *fakeCmd {
-		return &fakeCmd{output: []byte("Connected")}
-	}
-	// Patch middleware
-	getLastConnectedProfile = mockGetLastConnectedProfile
+func TestConnectWithRetries_AlreadyConnectedDifferentProfile(t *testing.T) {
+	f := setupFakeVPN(t, "state: Connected")
+	profile, _ := config.ResolveProfile("dev")
+	setLastConnected(t, "intra")
 
-	connectWithRetries(&model.CREDENTIAL_FOR_LOGIN{}, "dev", 0)
-	assert.Contains(t, logInfoMsgs[len(logInfoMsgs)-1], "VPN already connected to profile")
+	assert.NoError(t, connectWithRetries(ciscoBackend(t), testCredential(), profile))
+	assert.Contains(t, f.calls(), "disconnect")
+	assert.Contains(t, f.calls(), "connect DEV-VPN-REMOTE -s")
 }
 
-func TestConnectWithRetries_AlreadyConnectedDifferentProfile(t *testing.T) {
-	resetLogger()
-	restore := patchHelpers()
-	defer restore()
-	killCiscoProcessesFunc = func() error { return nil }
-	disconnectCalled := false
-	disconnectFunc = func() { disconnectCalled = true }
-	launchGUIFunc = func() {}
+func TestConnectWithRetries_KillCiscoProcessesFails(t *testing.T) {
+	f := setupFakeVPN(t, "state: Disconnected")
+	bin := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(bin, "pgrep"), []byte("#!/bin/sh\necho 4242\n"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(bin, "kill"), []byte("#!/bin/sh\necho \"kill $*\" >> \"$FAKE_VPN_LOG\"\nexit 1\n"), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	profile, _ := config.ResolveProfile("intra")
+
+	assert.NoError(t, connectWithRetries(ciscoBackend(t), testCredential(), profile))
+	assert.Contains(t, f.calls(), "kill -9 4242")
+	assert.Contains(t, f.calls(), "connect INTRA -s", "a failed kill does not stop the connect")
+}
 
-	mockLastProfile = "intra"
-	mockGetProfileErr = nil
+func TestConnectWithRetries_NoPushForNonMFAProfile(t *testing.T) {
+	f := setupFakeVPN(t, "state: Disconnected")
+	profile, _ := config.ResolveProfile("intra")
 
-	execCommandContextFunc = func(ctx interface{}, name string, arg ...string) *fakeCmd {
-		return &fakeCmd{output: []byte("Connected")}
-	}
-	getLastConnectedProfile = mockGetLastConnectedProfile
+	assert.NoError(t, connectWithRetries(ciscoBackend(t), testCredential(), profile))
+	assert.Contains(t, f.calls(), "connect INTRA -s")
+	assert.Equal(t, "user\npass\nyflag\n", f.answers())
+}
+
+func TestConnectWithRetries_HappyPath(t *testing.T) {
+	f := setupFakeVPN(t, "state: Disconnected")
+	t.Setenv("FAKE_VPN_PROMPTS", "Username: |Password: |accept? [y/n]: |Second Password: ")
+	profile, _ := config.ResolveProfile("dev")
 
-	connectWithRetries(&model.CREDENTIAL_FOR_LOGIN{}, "dev", 0)
-	assert.True(t, disconnectCalled)
+	assert.NoError(t, connectWithRetries(ciscoBackend(t), testCredential(), profile))
+	assert.Contains(t, f.calls(), "connect DEV-VPN-REMOTE -s")
+	assert.Equal(t, "user\npass\nyflag\npush\n", f.answers())
 }
 
-func TestConnectWithRetries_KillCiscoProcessesFails(t *testing.T) {
-	resetLogger()
-	restore := patchHelpers()
-	defer restore()
-	killCiscoProcessesFunc = func() error { return errors.New("fail") }
-	disconnectFunc = func() {}
-	launchGUIFunc = func() {}
+// fakeBackend records what the connect flow asks of it.
+type fakeBackend struct {
+	state        string
+	connected    *model.Profile
+	credential   *model.CREDENTIAL_FOR_LOGIN
+	connects     int
+	disconnected bool
+	connectErr   error
+}
 
-	mockLastProfile = ""
-	mockGetProfileErr = nil
+func (f *fakeBackend) Name() string { return "fake" }
+func (f *fakeBackend) Connect(ctx context.Context, profile *model.Profile, credential *model.CREDENTIAL_FOR_LOGIN) error {
+	f.connected, f.credential = profile, credential
+	f.connects++
+	return f.connectErr
+}
+func (f *fakeBackend) Disconnect(ctx context.Context) error { f.disconnected = true; return nil }
+func (f *fakeBackend) Status(ctx context.Context) (*model.VPNState, error) {
+	return &model.VPNState{State: f.state}, nil
+}
+func (f *fakeBackend) Capabilities() backend.Capabilities { return backend.Capabilities{} }
 
-	execCommandContextFunc = func(ctx interface{}, name string, arg ...string) *fakeCmd {
-		return &fakeCmd{output: []byte("Not Connected")}
-	}
-	getLastConnectedProfile = mockGetLastConnectedProfile
+func TestConnectWithRetries_UsesProfileBackend(t *testing.T) {
+	setupFakeVPN(t, "")
+	profile, _ := config.ResolveProfile("intra")
+	setLastConnected(t, "dev")
 
-	// Patch os.ReadFile to fail
-	restoreFiles := patchFileOps()
-	defer restoreFiles()
-	readFileFunc = func(path string) ([]byte, error) { return nil, errors.New("fail") }
+	fake := &fakeBackend{state: model.StateConnected}
+	assert.NoError(t, connectWithRetries(fake, testCredential(), profile))
+	assert.True(t, fake.disconnected)
+	assert.Equal(t, "intra", fake.connected.Name)
 
-	connectWithRetries(&model.CREDENTIAL_FOR_LOGIN{}, "dev", 0)
-	assert.Contains(t, logErrorMsgs[len(logErrorMsgs)-1], "reading VPN profile")
+	last, err := middleware.GetLastConnectedProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, "intra", last)
 }
 
-func TestConnectWithRetries_HappyPath(t *testing.T) {
-	resetLogger()
-	restore := patchHelpers()
-	defer restore()
-	killCiscoProcessesFunc = func() error { return nil }
-	disconnectFunc = func() {}
-	launchCalled := false
-	launchGUIFunc = func() { launchCalled = true }
+func TestConnectWithRetries_AgentLockIsNotRecorded(t *testing.T) {
+	setupFakeVPN(t, "")
+	profile, _ := config.ResolveProfile("intra")
+	setLastConnected(t, "dev")
 
-	mockLastProfile = ""
-	mockGetProfileErr = nil
-	mockSetProfileErr = nil
+	fast := *profile
+	fast.Retry.BaseDelay, fast.Retry.MaxDelay = "1ms", "1ms"
 
-	execCommandContextFunc = func(ctx interface{}, name string, arg ...string) *fakeCmd {
-		return &fakeCmd{output: []byte("Not Connected")}
-	}
-	getLastConnectedProfile = mockGetLastConnectedProfile
-	setLastConnectedProfile = mockSetLastConnectedProfile
+	fake := &fakeBackend{state: model.StateDisconnected, connectErr: backend.ErrAgentLocked}
+	err := connectWithRetries(fake, testCredential(), &fast)
+	assert.ErrorIs(t, err, backend.ErrAgentLocked)
+	assert.Equal(t, config.VPN_CONNECTION_RETRY_COUNT+1, fake.connects)
 
-	restoreFiles := patchFileOps()
-	defer restoreFiles()
-	readFileFunc = func(path string) ([]byte, error) {
-		return []byte("script with {{USERNAME}} and {{PASSWORD}} and {{Y}}"), nil
-	}
-	writeFileFunc = func(path string, data []byte, perm os.FileMode) error { return nil }
-	openFileFunc = func(path string) (*os.File, error) {
-		return os.NewFile(0, os.DevNull), nil
-	}
-	removeFunc = func(path string) error { return nil }
+	last, _ := middleware.GetLastConnectedProfile()
+	assert.Equal(t, "dev", last)
+}
 
-	// Patch exec.Command for connect
-	execCommandFunc = func(name string, arg ...string) *fakeCmd {
-		return &fakeCmd{
-			stdout:   bytes.NewBufferString("All good\n"),
-			stderr:   bytes.NewBufferString(""),
-			startErr: nil,
-			waitErr:  nil,
-		}
-	}
+func TestConnectWithRetries_AuthFailureIsNotRetried(t *testing.T) {
+	setupFakeVPN(t, "")
+	profile, _ := config.ResolveProfile("intra")
 
-	cred := &model.CREDENTIAL_FOR_LOGIN{
-		Username: "user",
-		Password: "pass",
-		YFlag:    "yflag",
-		Push:     "push",
-	}
-	connectWithRetries(cred, "dev", 0)
-	assert.True(t, launchCalled)
+	fake := &fakeBackend{state: model.StateDisconnected, connectErr: backend.ErrAuthFailed}
+	assert.ErrorIs(t, connectWithRetries(fake, testCredential(), profile), backend.ErrAuthFailed)
+	assert.Equal(t, 1, fake.connects)
 }
 
-// Add more tests for error branches as needed...
+func ciscoBackend(t *testing.T) backend.Backend {
+	t.Helper()
+	b, err := backend.Get("cisco")
+	assert.NoError(t, err)
+	return b
+}
+
+func setLastConnected(t *testing.T, profile string) {
+	t.Helper()
+	assert.NoError(t, middleware.SetLastConnectedProfile(profile))
+}
+
+func TestStatus_ExitCodes(t *testing.T) {
+	setupFakeVPN(t, "  >> state: Connected")
+	assert.Equal(t, ExitConnected, Status("json"))
+
+	setupFakeVPN(t, "  >> state: Disconnected")
+	assert.Equal(t, ExitDisconnected, Status("table"))
+
+	setupFakeVPN(t, "  >> state: Reconnecting")
+	assert.Equal(t, ExitUnknown, Status("yaml"))
+
+	assert.Equal(t, ExitUsage, Status("xml"))
+}
+
+func TestPrintState(t *testing.T) {
+	since := time.Date(2025, 6, 19, 12, 0, 0, 0, time.UTC)
+	state := &model.VPNState{State: model.StateConnected, Profile: "dev", Host: "vpn.example.com", ClientAddress: "10.1.2.3", ConnectedSince: &since}
+
+	var out bytes.Buffer
+	assert.NoError(t, printState(&out, state, "json"))
+	assert.Contains(t, out.String(), `"client_address": "10.1.2.3"`)
+	assert.Contains(t, out.String(), `"connected_since": "2025-06-19T12:00:00Z"`)
+
+	out.Reset()
+	assert.NoError(t, printState(&out, state, "yaml"))
+	assert.Contains(t, out.String(), "profile: dev")
+
+	out.Reset()
+	assert.NoError(t, printState(&out, state, "table"))
+	assert.Contains(t, out.String(), "Host")
+	assert.Contains(t, out.String(), "vpn.example.com")
+}
@@ -9,24 +9,53 @@ package config
 import (
 	_ "embed"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/BurntSushi/toml"
 	"github.com/goo-apps/vpnctl/internal/model"
 )
 
 var (
 	VPN_BINARY_PATH            string
 	VPN_GUI_PATH               string
+	VPN_BINARY_SOURCE          string // where VPN_BINARY_PATH came from: config, env, cache or discovery
+	VPN_GUI_SOURCE             string // where VPN_GUI_PATH came from
 	VPN_CONNECTION_RETRY_COUNT int
+	VPN_AGENT_ADDRESS          string
+	VPN_AGENT_WAIT             time.Duration
+	VPN_RESTART_AGENT          bool
+	OPENCONNECT_BINARY_PATH    string
+	OPENCONNECT_PID_FILE       string
+	OPENVPN_BINARY_PATH        string
+	OPENVPN_MANAGEMENT_SOCKET  string
+	OPENVPN_PID_FILE           string
+	WIREGUARD_WG_QUICK_PATH    string
+	WIREGUARD_WG_PATH          string
+	WIREGUARD_STATE_FILE       string
 	SQLITE_DB_PATH             string
 	APPLICATION_ENVIRONMENT    string
 	KEYRING_SERVICE_NAME       string
 	KEYRING_ENCRYPTION_KEY     string
+	CREDENTIAL_STORE           string
+	CREDENTIAL_FILE            string
+	CREDENTIAL_MAX_AGE_DAYS    int // 0 when the password lifetime is learned
 	LOGGER_LEVEL               int
 	APPLICATION_VERSION        string
+	VPN_PROFILES               []model.Profile
+	CONFIG_SOURCE              string // configuration layers in use, lowest precedence first
+
+	DAEMON_POLL_INTERVAL           time.Duration
+	DAEMON_MIN_RECONNECT_INTERVAL  time.Duration
+	DAEMON_MAX_RECONNECTS_PER_HOUR int
+	DAEMON_AUTH_FAILURE_LIMIT      int
+	DAEMON_BREAKER_COOLDOWN        time.Duration
+	DAEMON_SOCKET                  string
+	DAEMON_METRICS_ADDRESS         string
 )
 
 type ConfigReader struct {
@@ -38,29 +67,23 @@ type ConfigReader struct {
 //go:embed resource.toml
 var embeddedConfig []byte
 
-// LoadConfig loads config from embedded file or optionally from external path if given
+// LoadConfig merges the configuration layers: the embedded resource.toml,
+// SystemConfigPath, UserConfigPath, the file at path (if given), VPNCTL_*
+// environment variables and --set flags. See layers.go.
 func LoadConfig(path string) (*model.Config, error) {
-	cfg := &model.Config{}
-
-	if path == "" {
-		// Load from embedded
-		err := toml.Unmarshal(embeddedConfig, cfg)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse embedded config: %w", err)
-		}
-		return cfg, nil
+	layers, err := fileLayers(path)
+	if err != nil {
+		return nil, err
 	}
-
-	// If user explicitly passed path, load from file system (optional fallback)
-	data, err := os.ReadFile(path)
+	merged, err := merge(layers, os.LookupEnv, flagOverrides)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		return nil, err
 	}
-	err = toml.Unmarshal(data, cfg)
+	cfg, err := merged.decode()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, err
 	}
-
+	loaded = merged
 	return cfg, nil
 }
 
@@ -139,18 +162,71 @@ func (c *ConfigReader) GetBool(path string) bool {
 func LoadAllConfigAtOnce(configPath string) error {
 	vr, err := LoadConfig(configPath)
 	if err != nil {
-		fmt.Errorf("failed to load configuration: %w", err)
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	profiles, err := normalizeProfiles(vr.Profiles, vr.VPN.ConnectionRetry)
+	if err != nil {
+		return fmt.Errorf("invalid profile configuration: %w", err)
+	}
+	agentWait, err := durationOr("[vpn] agent_wait", vr.VPN.AgentWait, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	pollInterval, err := durationOr("[daemon] poll_interval", vr.Daemon.PollInterval, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	minReconnectInterval, err := durationOr("[daemon] min_reconnect_interval", vr.Daemon.MinReconnectInterval, time.Minute)
+	if err != nil {
+		return err
+	}
+	breakerCooldown, err := durationOr("[daemon] breaker_cooldown", vr.Daemon.BreakerCooldown, 30*time.Minute)
+	if err != nil {
+		return err
+	}
+	if vr.Credential.PasswordMaxAgeDays < 0 {
+		return fmt.Errorf("[credential] password_max_age_days must be greater than or equal to 0")
 	}
 
 	VPN_BINARY_PATH = vr.VPN.BinaryPath
 	VPN_GUI_PATH = vr.VPN.GuiPath
 	VPN_CONNECTION_RETRY_COUNT = vr.VPN.ConnectionRetry
+	VPN_AGENT_ADDRESS = vr.VPN.AgentAddress
+	VPN_AGENT_WAIT = agentWait
+	VPN_RESTART_AGENT = vr.VPN.RestartAgent
+	OPENCONNECT_BINARY_PATH = vr.OpenConnect.BinaryPath
+	OPENCONNECT_PID_FILE = vr.OpenConnect.PidFile
+	OPENVPN_BINARY_PATH = vr.OpenVPN.BinaryPath
+	OPENVPN_MANAGEMENT_SOCKET = vr.OpenVPN.ManagementSocket
+	OPENVPN_PID_FILE = vr.OpenVPN.PidFile
+	WIREGUARD_WG_QUICK_PATH = vr.WireGuard.WgQuickPath
+	WIREGUARD_WG_PATH = vr.WireGuard.WgPath
+	WIREGUARD_STATE_FILE = vr.WireGuard.StateFile
 	SQLITE_DB_PATH = vr.Sqlite.Path
 	APPLICATION_ENVIRONMENT = vr.Application.Environment
 	KEYRING_SERVICE_NAME = vr.Keyring.ServiceName
 	KEYRING_ENCRYPTION_KEY = vr.Keyring.EncryptionKey
+	CREDENTIAL_STORE = vr.Credential.Store
+	CREDENTIAL_FILE = vr.Credential.File
+	if CREDENTIAL_FILE == "" {
+		CREDENTIAL_FILE = "~/.vpnctl/credentials.enc"
+	}
+	CREDENTIAL_MAX_AGE_DAYS = vr.Credential.PasswordMaxAgeDays
 	LOGGER_LEVEL = vr.Logger.LoggerLevel
 	APPLICATION_VERSION = vr.Application.Version
+	VPN_PROFILES = profiles
+	CONFIG_SOURCE = strings.Join(loaded.sources, " < ")
+	DAEMON_POLL_INTERVAL = pollInterval
+	DAEMON_MIN_RECONNECT_INTERVAL = minReconnectInterval
+	DAEMON_MAX_RECONNECTS_PER_HOUR = vr.Daemon.MaxReconnectsPerHour
+	DAEMON_AUTH_FAILURE_LIMIT = vr.Daemon.AuthFailureLimit
+	DAEMON_BREAKER_COOLDOWN = breakerCooldown
+	DAEMON_SOCKET = vr.Daemon.Socket
+	if DAEMON_SOCKET == "" {
+		DAEMON_SOCKET = "~/.vpnctl/vpnctl.sock"
+	}
+	DAEMON_METRICS_ADDRESS = vr.Daemon.MetricsAddress
 
 	// print all loaded configurations for debugging
 	// for key, value := range vr.data {
@@ -188,3 +264,196 @@ func LoadAllConfigAtOnce(configPath string) error {
 
 	return nil
 }
+
+// durationOr parses a duration setting, returning def when it is not set.
+func durationOr(name, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	return d, nil
+}
+
+// normalizeProfiles validates the [[profile]] tables and fills in defaults.
+// Names and aliases must be unique (case-insensitive) across all profiles.
+// retries is [vpn] connection_retry, the default number of retries after the first attempt.
+func normalizeProfiles(profiles []model.Profile, retries int) ([]model.Profile, error) {
+	seen := make(map[string]string)
+	out := make([]model.Profile, 0, len(profiles))
+	for i, p := range profiles {
+		p.Name = strings.TrimSpace(p.Name)
+		if p.Name == "" {
+			return nil, fmt.Errorf("profile #%d has no name", i+1)
+		}
+		if strings.TrimSpace(p.Host) == "" {
+			return nil, fmt.Errorf("profile %q has no host", p.Name)
+		}
+		if p.Backend == "" {
+			p.Backend = "cisco"
+		}
+		if p.MFA == "" {
+			p.MFA = "none"
+		}
+		if p.MFA != "none" && p.MFA != "push" && p.MFA != "totp" {
+			return nil, fmt.Errorf("profile %q has unsupported mfa mode %q", p.Name, p.MFA)
+		}
+		if p.PasswordMaxAgeDays < 0 {
+			return nil, fmt.Errorf("profile %q password_max_age_days must be greater than or equal to 0", p.Name)
+		}
+		if len(p.Prompts) == 0 {
+			p.Prompts = defaultPromptRules(p.MFA)
+		}
+		for _, rule := range p.Prompts {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return nil, fmt.Errorf("profile %q has an invalid prompt pattern %q: %w", p.Name, rule.Pattern, err)
+			}
+		}
+		retry, err := normalizeRetryPolicy(p.Retry, retries)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		p.Retry = retry
+		for j, probe := range p.Probes {
+			if p.Probes[j], err = normalizeProbe(probe); err != nil {
+				return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+			}
+		}
+		for _, key := range append([]string{p.Name}, p.Aliases...) {
+			key = strings.ToLower(key)
+			if owner, ok := seen[key]; ok {
+				return nil, fmt.Errorf("profile name or alias %q is used by both %q and %q", key, owner, p.Name)
+			}
+			seen[key] = p.Name
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// defaultRetryActions is the policy for failure classes a profile does not configure:
+// a locked agent is recovered, a rejected password is never retried and transient
+// network or server trouble is retried with backoff.
+var defaultRetryActions = map[string]string{
+	model.FailureAgentLocked: model.RetryActionRecoverAgent,
+	model.FailureAuth:        model.RetryActionAbort,
+	model.FailureNetwork:     model.RetryActionRetry,
+	model.FailureServerBusy:  model.RetryActionRetry,
+	model.FailureTimeout:     model.RetryActionRetry,
+	model.FailureProbe:       model.RetryActionRetry,
+	model.FailureOther:       model.RetryActionAbort,
+}
+
+// normalizeRetryPolicy validates a [profile.retry] table and fills in defaults.
+func normalizeRetryPolicy(policy model.RetryPolicy, retries int) (model.RetryPolicy, error) {
+	if policy.MaxAttempts < 0 {
+		return policy, fmt.Errorf("retry max_attempts must be greater than or equal to 0")
+	}
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = max(retries, 0) + 1
+	}
+	if policy.BaseDelay == "" {
+		policy.BaseDelay = "2s"
+	}
+	if policy.MaxDelay == "" {
+		policy.MaxDelay = "30s"
+	}
+	for _, d := range []string{policy.BaseDelay, policy.MaxDelay} {
+		if _, err := time.ParseDuration(d); err != nil {
+			return policy, fmt.Errorf("invalid retry delay %q: %w", d, err)
+		}
+	}
+
+	actions := make(map[string]string, len(defaultRetryActions))
+	for class, action := range defaultRetryActions {
+		actions[class] = action
+	}
+	for class, action := range policy.On {
+		if _, ok := defaultRetryActions[class]; !ok {
+			return policy, fmt.Errorf("unknown retry failure class %q", class)
+		}
+		switch action {
+		case model.RetryActionRetry, model.RetryActionRecoverAgent, model.RetryActionAbort:
+		default:
+			return policy, fmt.Errorf("unknown retry action %q for %s, use retry, recover_agent or abort", action, class)
+		}
+		actions[class] = action
+	}
+	policy.On = actions
+	return policy, nil
+}
+
+// normalizeProbe validates a [[profile.probe]] table and fills in defaults:
+// the target as name, expected status 200 and a 5s timeout.
+func normalizeProbe(probe model.Probe) (model.Probe, error) {
+	if strings.TrimSpace(probe.Target) == "" {
+		return probe, fmt.Errorf("probe %q has no target", probe.Name)
+	}
+	if probe.Name == "" {
+		probe.Name = probe.Target
+	}
+	switch probe.Type {
+	case model.ProbeTCP:
+		if _, _, err := net.SplitHostPort(probe.Target); err != nil {
+			return probe, fmt.Errorf("probe %q: tcp target must be host:port: %w", probe.Name, err)
+		}
+	case model.ProbeHTTP:
+		if u, err := url.Parse(probe.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return probe, fmt.Errorf("probe %q: http target must be an http(s) URL", probe.Name)
+		}
+		if probe.ExpectStatus == 0 {
+			probe.ExpectStatus = 200
+		}
+	case model.ProbeDNS:
+	default:
+		return probe, fmt.Errorf("probe %q has unsupported type %q, use tcp, http or dns", probe.Name, probe.Type)
+	}
+	if probe.Timeout == "" {
+		probe.Timeout = "5s"
+	}
+	if d, err := time.ParseDuration(probe.Timeout); err != nil || d <= 0 {
+		return probe, fmt.Errorf("probe %q has an invalid timeout %q", probe.Name, probe.Timeout)
+	}
+	return probe, nil
+}
+
+// defaultPromptRules answers the Cisco CLI prompts for the given mfa mode.
+// "Second Password:" must come before "Password:" as the first matching rule wins.
+func defaultPromptRules(mfa string) []model.PromptRule {
+	rules := []model.PromptRule{{Pattern: `(?i)username:`, Answer: "username"}}
+	if mfa == "push" || mfa == "totp" {
+		rules = append(rules, model.PromptRule{Pattern: `(?i)second password:`, Answer: mfa})
+	}
+	return append(rules,
+		model.PromptRule{Pattern: `(?i)password:`, Answer: "password"},
+		model.PromptRule{Pattern: `(?i)accept\?\s*\[y/n\]`, Answer: "yflag"},
+	)
+}
+
+// ResolveProfile looks up a configured profile by name or alias.
+// Unknown names fail with the list of configured profiles.
+func ResolveProfile(name string) (*model.Profile, error) {
+	for i := range VPN_PROFILES {
+		p := &VPN_PROFILES[i]
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+		for _, alias := range p.Aliases {
+			if strings.EqualFold(alias, name) {
+				return p, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown VPN profile %q, configured profiles: %s", name, strings.Join(ProfileNames(), ", "))
+}
+
+// ProfileNames returns the names of all configured profiles in declaration order.
+func ProfileNames() []string {
+	names := make([]string, 0, len(VPN_PROFILES))
+	for _, p := range VPN_PROFILES {
+		names = append(names, p.Name)
+	}
+	return names
+}
@@ -0,0 +1,796 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+// The configuration is merged from layers, lowest precedence first: the embedded
+// resource.toml, SystemConfigPath, UserConfigPath, the CONFIG_PATH file, VPNCTL_*
+// environment variables and --set flags. Tables merge key by key; arrays such as
+// [[profile]] are replaced as a whole by the highest layer that sets them.
+var (
+	SystemConfigPath = "/etc/vpnctl/config.toml"
+	UserConfigPath   = "~/.vpnctl/config.toml"
+)
+
+// EnvPrefix prefixes environment overrides: [vpn] binary_path is VPNCTL_VPN_BINARY_PATH.
+const EnvPrefix = "VPNCTL_"
+
+// Origin sources besides file paths.
+const (
+	OriginEmbedded = "embedded"
+	OriginEnv      = "env"
+	OriginFlag     = "flag"
+)
+
+// secretKeys are masked by `vpnctl config show`.
+var secretKeys = []string{"keyring.encryption_key"}
+
+// flagOverrides holds the --set key=value flags, see ParseFlags.
+var flagOverrides []string
+
+// loaded is the merged configuration of the last LoadConfig.
+var loaded *layered
+
+// Origin tells where a configuration value came from.
+type Origin struct {
+	Source string // OriginEmbedded, a file path, OriginEnv or OriginFlag
+	Line   int    // line in the file, 0 when unknown
+	Detail string // environment variable or flag
+}
+
+func (o Origin) String() string {
+	switch {
+	case o.Line > 0:
+		return fmt.Sprintf("%s:%d", o.Source, o.Line)
+	case o.Detail != "":
+		return fmt.Sprintf("%s %s", o.Source, o.Detail)
+	}
+	return o.Source
+}
+
+// Setting is one configuration value with its origin. Keys are dotted, array
+// tables are indexed: vpn.binary_path, profile[0].host.
+type Setting struct {
+	Key    string
+	Value  any
+	Origin Origin
+}
+
+// Problem is a configuration error found by Validate.
+type Problem struct {
+	Origin  Origin
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Origin, p.Message)
+}
+
+// layer is one parsed configuration file.
+type layer struct {
+	source    string
+	data      map[string]any
+	lines     map[string]int // dotted key -> line, see keyLines
+	undecoded []string       // keys the schema does not know
+}
+
+// layered is the merged configuration with the origin of every key.
+type layered struct {
+	data    map[string]any
+	origins map[string]Origin
+	sources []string
+}
+
+// fileError is a configuration file that cannot be parsed.
+type fileError struct {
+	path string
+	err  error
+}
+
+func (e *fileError) Error() string { return fmt.Sprintf("%s: %v", e.path, e.err) }
+func (e *fileError) Unwrap() error { return e.err }
+
+// ParseFlags takes the leading global --set key=value flags off the command line
+// and returns the remaining arguments. The values override every other layer.
+func ParseFlags(args []string) ([]string, error) {
+	for len(args) > 0 {
+		var kv string
+		switch {
+		case args[0] == "--set":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("--set needs a key=value argument")
+			}
+			kv, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--set="):
+			kv, args = strings.TrimPrefix(args[0], "--set="), args[1:]
+		default:
+			return args, nil
+		}
+		if !strings.Contains(kv, "=") {
+			return nil, fmt.Errorf("--set %q: expected key=value", kv)
+		}
+		flagOverrides = append(flagOverrides, kv)
+	}
+	return args, nil
+}
+
+// readLayer parses one configuration file. Syntax and type errors carry the line.
+func readLayer(source string, data []byte) (*layer, error) {
+	md, err := toml.Decode(string(data), &model.Config{})
+	if err != nil {
+		return nil, err
+	}
+	l := &layer{source: source, data: map[string]any{}, lines: keyLines(data)}
+	if err := toml.Unmarshal(data, &l.data); err != nil {
+		return nil, err
+	}
+	for _, key := range md.Undecoded() {
+		l.undecoded = append(l.undecoded, key.String())
+	}
+	return l, nil
+}
+
+// fileLayers reads the embedded defaults and the configuration files that exist;
+// explicit (CONFIG_PATH) must exist when set.
+func fileLayers(explicit string) ([]*layer, error) {
+	embedded, err := embeddedLayer()
+	if err != nil {
+		return nil, err
+	}
+	layers := []*layer{embedded}
+	for _, path := range []string{SystemConfigPath, expandHome(UserConfigPath), explicit} {
+		if path == "" {
+			continue
+		}
+		l, err := fileLayer(path)
+		if errors.Is(err, os.ErrNotExist) && path != explicit {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+	return layers, nil
+}
+
+func embeddedLayer() (*layer, error) {
+	l, err := readLayer(OriginEmbedded, embeddedConfig)
+	if err != nil {
+		return nil, &fileError{OriginEmbedded, err}
+	}
+	l.lines = nil // the embedded file is not something users can edit
+	return l, nil
+}
+
+func fileLayer(path string) (*layer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	l, err := readLayer(path, data)
+	if err != nil {
+		return nil, &fileError{path, err}
+	}
+	return l, nil
+}
+
+// merge stacks the file layers, then applies the environment and the flags.
+func merge(layers []*layer, environ func(string) (string, bool), flags []string) (*layered, error) {
+	m := &layered{data: map[string]any{}, origins: map[string]Origin{}}
+	for _, l := range layers {
+		m.mergeTable(m.data, l.data, "", l)
+		m.sources = append(m.sources, l.source)
+	}
+
+	var envKeys []string
+	for _, s := range m.settings() {
+		if _, list := s.Value.([]any); !list && !strings.Contains(s.Key, "[") {
+			envKeys = append(envKeys, s.Key)
+		}
+	}
+	envUsed := false
+	for _, key := range envKeys {
+		name := EnvName(key)
+		raw, ok := environ(name)
+		if !ok {
+			continue
+		}
+		if err := m.set(key, raw, Origin{Source: OriginEnv, Detail: name}); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		envUsed = true
+	}
+	if envUsed {
+		m.sources = append(m.sources, OriginEnv)
+	}
+
+	for _, kv := range flags {
+		key, raw, _ := strings.Cut(kv, "=")
+		if err := m.set(key, raw, Origin{Source: OriginFlag, Detail: "--set " + kv}); err != nil {
+			return nil, fmt.Errorf("--set %s: %w", kv, err)
+		}
+	}
+	if len(flags) > 0 {
+		m.sources = append(m.sources, OriginFlag)
+	}
+	return m, nil
+}
+
+// mergeTable copies src into dst, recording the layer as origin of every key it sets.
+func (m *layered) mergeTable(dst, src map[string]any, prefix string, l *layer) {
+	for k, v := range src {
+		key := joinKey(prefix, k)
+		if table, ok := v.(map[string]any); ok {
+			sub, ok := dst[k].(map[string]any)
+			if !ok {
+				sub = map[string]any{}
+				dst[k] = sub
+			}
+			m.mergeTable(sub, table, key, l)
+			continue
+		}
+		dst[k] = v
+		for existing := range m.origins {
+			if strings.HasPrefix(existing, key+"[") {
+				delete(m.origins, existing) // a replaced array
+			}
+		}
+		values := map[string]any{}
+		flatten(v, key, values)
+		for child := range values {
+			m.origins[child] = Origin{Source: l.source, Line: l.lines[child]}
+		}
+		m.origins[key] = Origin{Source: l.source, Line: l.lines[key]}
+	}
+}
+
+// set overrides an existing scalar key from text, keeping its type.
+func (m *layered) set(key, raw string, origin Origin) error {
+	parts := strings.Split(key, ".")
+	table := m.data
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := table[part].(map[string]any)
+		if !ok {
+			return fmt.Errorf("unknown configuration key %q", key)
+		}
+		table = next
+	}
+	last := parts[len(parts)-1]
+	current, ok := table[last]
+	if !ok {
+		return fmt.Errorf("unknown configuration key %q", key)
+	}
+	value, err := parseValue(key, current, raw)
+	if err != nil {
+		return err
+	}
+	table[last] = value
+	m.origins[key] = origin
+	return nil
+}
+
+// settings flattens the merged configuration, sorted by key.
+func (m *layered) settings() []Setting {
+	values := map[string]any{}
+	flatten(m.data, "", values)
+	settings := make([]Setting, 0, len(values))
+	for key, value := range values {
+		settings = append(settings, Setting{Key: key, Value: value, Origin: m.origins[key]})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// decode turns the merged configuration into the model.
+func (m *layered) decode() (*model.Config, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(m.data); err != nil {
+		return nil, fmt.Errorf("encoding merged configuration: %w", err)
+	}
+	cfg := &model.Config{}
+	if err := toml.Unmarshal(buf.Bytes(), cfg); err != nil {
+		return nil, fmt.Errorf("decoding merged configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// flatten collects the scalar and plain-array leaves of a value under dotted keys;
+// arrays of tables are indexed.
+func flatten(v any, prefix string, out map[string]any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			flatten(child, joinKey(prefix, k), out)
+		}
+	case []map[string]any:
+		for i, child := range v {
+			flatten(child, fmt.Sprintf("%s[%d]", prefix, i), out)
+		}
+	default:
+		out[prefix] = v
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// EnvName is the environment variable overriding a key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// parseValue parses text as the type of the value it replaces.
+func parseValue(key string, current any, raw string) (any, error) {
+	switch current.(type) {
+	case string:
+		return raw, nil
+	case int64:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer, got %q", key, raw)
+		}
+		return v, nil
+	case bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", key, raw)
+		}
+		return v, nil
+	case float64:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number, got %q", key, raw)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s cannot be set from the command line, edit %s", key, UserConfigPath)
+	}
+}
+
+// formatValue renders a value as a TOML literal.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatValue(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+var (
+	indexPattern   = regexp.MustCompile(`\[\d+\]`)
+	keyLinePattern = regexp.MustCompile(`^\s*("[^"]*"|[A-Za-z0-9_.-]+)\s*=`)
+)
+
+// keyLines maps the dotted keys of a TOML document to their line numbers. Array
+// tables are indexed (profile[0].host); the first occurrence of a key is also
+// recorded without indices (profile.host), which is how the decoder names it.
+func keyLines(data []byte) map[string]int {
+	lines := map[string]int{}
+	arrays := map[string]int{} // array table -> index of its current element
+	counts := map[string]int{}
+	indexed := func(plain string) string {
+		parts := strings.Split(plain, ".")
+		out := ""
+		for i, part := range parts {
+			out = joinKey(out, part)
+			if idx, ok := arrays[strings.Join(parts[:i+1], ".")]; ok {
+				out += fmt.Sprintf("[%d]", idx)
+			}
+		}
+		return out
+	}
+	record := func(key string, n int) {
+		lines[key] = n
+		if plain := indexPattern.ReplaceAllString(key, ""); lines[plain] == 0 {
+			lines[plain] = n
+		}
+	}
+
+	prefix := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		n := i + 1
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "[["):
+			name := strings.TrimSpace(line[2:strings.Index(line+"]]", "]]")])
+			parent, last := "", name
+			if dot := strings.LastIndex(name, "."); dot >= 0 {
+				parent, last = indexed(name[:dot]), name[dot+1:]
+			}
+			counter := joinKey(parent, last)
+			arrays[name] = counts[counter]
+			counts[counter]++
+			prefix = indexed(name)
+			record(prefix, n)
+		case strings.HasPrefix(line, "["):
+			name := strings.TrimSpace(line[1:strings.Index(line+"]", "]")])
+			prefix = indexed(name)
+			record(prefix, n)
+		default:
+			if m := keyLinePattern.FindStringSubmatch(line); m != nil {
+				record(joinKey(prefix, strings.Trim(m[1], `"`)), n)
+			}
+		}
+	}
+	return lines
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// Settings returns every configuration value with its origin, sorted by key.
+func Settings() []Setting {
+	if loaded == nil {
+		return nil
+	}
+	return loaded.settings()
+}
+
+// Lookup returns the setting with the given key, or every setting of a table
+// (e.g. "daemon" or "profile[0]").
+func Lookup(key string) ([]Setting, error) {
+	var found []Setting
+	for _, s := range Settings() {
+		if s.Key == key || strings.HasPrefix(s.Key, key+".") || strings.HasPrefix(s.Key, key+"[") {
+			found = append(found, s)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("unknown configuration key %q", key)
+	}
+	return found, nil
+}
+
+// OriginOf tells where the value of a key came from.
+func OriginOf(key string) Origin {
+	if loaded == nil {
+		return Origin{}
+	}
+	return loaded.origins[key]
+}
+
+// IsSecret reports whether a key holds a secret that is not printed.
+func IsSecret(key string) bool {
+	for _, secret := range secretKeys {
+		if key == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatValue renders a setting's value as a TOML literal.
+func FormatValue(v any) string {
+	return formatValue(v)
+}
+
+// SetUserValue writes key = value to the user configuration file, keeping its
+// comments and layout, and returns the file path. Only existing scalar keys of
+// plain tables can be set; the value must parse as the key's type.
+func SetUserValue(key, raw string) (string, error) {
+	if loaded == nil {
+		return "", fmt.Errorf("configuration is not loaded")
+	}
+	if strings.Contains(key, "[") {
+		return "", fmt.Errorf("%s is part of an array table, edit %s instead", key, UserConfigPath)
+	}
+	candidate := &layered{data: map[string]any{}, origins: map[string]Origin{}}
+	candidate.mergeTable(candidate.data, loaded.data, "", &layer{})
+	if err := candidate.set(key, raw, Origin{}); err != nil {
+		return "", err
+	}
+	section, name := "", key
+	if dot := strings.LastIndex(key, "."); dot >= 0 {
+		section, name = key[:dot], key[dot+1:]
+	}
+	literal := formatValue(candidate.value(key))
+
+	path := expandHome(UserConfigPath)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	updated := setLine(data, section, name, literal)
+	if _, err := readLayer(path, updated); err != nil {
+		return "", fmt.Errorf("refusing to write an invalid %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, updated, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// value returns the merged value of a key, nil when it is not set.
+func (m *layered) value(key string) any {
+	for _, s := range m.settings() {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return nil
+}
+
+// setLine replaces the line of section.name in a TOML document, or adds it to
+// the section, creating the section at the end when needed.
+func setLine(data []byte, section, name, literal string) []byte {
+	lines := strings.Split(string(data), "\n")
+	entry := fmt.Sprintf("%s = %s", name, literal)
+	if n, ok := keyLines(data)[joinKey(section, name)]; ok && n > 0 {
+		lines[n-1] = entry
+		return []byte(strings.Join(lines, "\n"))
+	}
+	if section == "" {
+		return []byte(entry + "\n" + string(data))
+	}
+	if n, ok := keyLines(data)[section]; ok && n > 0 {
+		lines = append(lines[:n], append([]string{entry}, lines[n:]...)...)
+		return []byte(strings.Join(lines, "\n"))
+	}
+	text := strings.TrimRight(string(data), "\n")
+	if text != "" {
+		text += "\n\n"
+	}
+	return []byte(fmt.Sprintf("%s[%s]\n%s\n", text, section, entry))
+}
+
+// ProfileTables returns the [[profile]] tables of the merged configuration as a
+// TOML document, as they were written, without the defaults vpnctl fills in.
+func ProfileTables() (string, error) {
+	if loaded == nil {
+		return "", fmt.Errorf("configuration is not loaded")
+	}
+	tables, _ := loaded.data["profile"].([]map[string]any)
+	return encodeProfiles(tables)
+}
+
+// ParseProfiles reads the [[profile]] tables of a TOML document.
+func ParseProfiles(doc string) ([]map[string]any, error) {
+	data := map[string]any{}
+	if err := toml.Unmarshal([]byte(doc), &data); err != nil {
+		return nil, fmt.Errorf("invalid profiles: %w", err)
+	}
+	tables, _ := data["profile"].([]map[string]any)
+	return tables, nil
+}
+
+// UserProfileTables returns the [[profile]] tables of the user configuration file
+// alone, nil when it sets none.
+func UserProfileTables() ([]map[string]any, error) {
+	data, err := os.ReadFile(expandHome(UserConfigPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseProfiles(string(data))
+}
+
+// SetUserProfiles replaces the [[profile]] tables of the user configuration file,
+// keeping its other settings. The tables replace the profiles of the lower layers.
+func SetUserProfiles(tables []map[string]any) (string, error) {
+	doc, err := encodeProfiles(tables)
+	if err != nil {
+		return "", err
+	}
+	path := expandHome(UserConfigPath)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	text := strings.TrimRight(string(stripProfiles(data)), "\n")
+	if text != "" {
+		text += "\n\n"
+	}
+	updated := []byte(text + doc)
+	if _, err := readLayer(path, updated); err != nil {
+		return "", fmt.Errorf("refusing to write an invalid %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, updated, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ProfilesShadowedBy returns the configuration file above the user configuration
+// whose [[profile]] tables replace the user's, so that profiles written by
+// SetUserProfiles would not take effect. It is empty when they would.
+func ProfilesShadowedBy() string {
+	switch source := OriginOf("profile").Source; source {
+	case "", OriginEmbedded, SystemConfigPath, expandHome(UserConfigPath):
+		return ""
+	default:
+		return source
+	}
+}
+
+func encodeProfiles(tables []map[string]any) (string, error) {
+	if len(tables) == 0 {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(map[string]any{"profile": tables}); err != nil {
+		return "", fmt.Errorf("encoding profiles: %w", err)
+	}
+	return buf.String(), nil
+}
+
+var tableHeaderPattern = regexp.MustCompile(`^\s*\[\[?\s*([A-Za-z0-9_.-]+)\s*\]\]?\s*(#.*)?$`)
+
+// stripProfiles removes the [[profile]] tables and their sub-tables from a TOML
+// document.
+func stripProfiles(data []byte) []byte {
+	var kept []string
+	inProfile := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if m := tableHeaderPattern.FindStringSubmatch(line); m != nil {
+			inProfile = m[1] == "profile" || strings.HasPrefix(m[1], "profile.")
+		}
+		if !inProfile {
+			kept = append(kept, line)
+		}
+	}
+	return []byte(strings.Join(kept, "\n"))
+}
+
+// Validate checks the configuration files, or only the given one on top of the
+// embedded defaults, and reports every problem with its file and line: syntax and
+// type errors, unknown keys and invalid values.
+func Validate(path string) []Problem {
+	var (
+		layers []*layer
+		err    error
+	)
+	if path == "" {
+		layers, err = fileLayers(os.Getenv("CONFIG_PATH"))
+	} else {
+		var embedded, l *layer
+		if embedded, err = embeddedLayer(); err == nil {
+			if l, err = fileLayer(path); err == nil {
+				layers = []*layer{embedded, l}
+			}
+		}
+	}
+	if err != nil {
+		return []Problem{problemFromError(err)}
+	}
+
+	var problems []Problem
+	for _, l := range layers {
+		for _, key := range l.undecoded {
+			problems = append(problems, Problem{
+				Origin:  Origin{Source: l.source, Line: l.lines[key]},
+				Message: fmt.Sprintf("unknown key %q", key),
+			})
+		}
+	}
+
+	environ := os.LookupEnv
+	flags := flagOverrides
+	if path != "" {
+		environ = func(string) (string, bool) { return "", false }
+		flags = nil
+	}
+	m, err := merge(layers, environ, flags)
+	if err != nil {
+		return append(problems, Problem{Origin: Origin{Source: OriginEnv}, Message: err.Error()})
+	}
+	cfg, err := m.decode()
+	if err != nil {
+		return append(problems, Problem{Message: err.Error()})
+	}
+	problems = append(problems, checkValues(cfg, m.origins)...)
+	rank := map[string]int{}
+	for i, l := range layers {
+		rank[l.source] = i
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i].Origin, problems[j].Origin
+		if rank[a.Source] != rank[b.Source] {
+			return rank[a.Source] < rank[b.Source]
+		}
+		return a.Line < b.Line
+	})
+	return problems
+}
+
+// decodeErrorPattern matches the decoder's type errors, which are not ParseErrors.
+var decodeErrorPattern = regexp.MustCompile(`^toml: line (\d+) \(last key "[^"]*"\): (.*)$`)
+
+// problemFromError turns a load error into a problem, with the line of TOML
+// syntax and type errors.
+func problemFromError(err error) Problem {
+	var fe *fileError
+	if !errors.As(err, &fe) {
+		return Problem{Message: err.Error()}
+	}
+	var pe toml.ParseError
+	if errors.As(err, &pe) {
+		return Problem{Origin: Origin{Source: fe.path, Line: pe.Position.Line}, Message: pe.Message}
+	}
+	if m := decodeErrorPattern.FindStringSubmatch(fe.err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return Problem{Origin: Origin{Source: fe.path, Line: line}, Message: m[2]}
+	}
+	return Problem{Origin: Origin{Source: fe.path}, Message: fe.err.Error()}
+}
+
+// checkValues reports invalid values of the merged configuration: durations and
+// the [[profile]] tables, each at the origin of the offending value.
+func checkValues(cfg *model.Config, origins map[string]Origin) []Problem {
+	var problems []Problem
+	durations := map[string]string{
+		"vpn.agent_wait":                cfg.VPN.AgentWait,
+		"daemon.poll_interval":          cfg.Daemon.PollInterval,
+		"daemon.min_reconnect_interval": cfg.Daemon.MinReconnectInterval,
+		"daemon.breaker_cooldown":       cfg.Daemon.BreakerCooldown,
+	}
+	for key, value := range durations {
+		if _, err := durationOr(key, value, 0); err != nil {
+			problems = append(problems, Problem{Origin: origins[key], Message: err.Error()})
+		}
+	}
+	if cfg.Credential.PasswordMaxAgeDays < 0 {
+		problems = append(problems, Problem{
+			Origin:  origins["credential.password_max_age_days"],
+			Message: "password_max_age_days must be greater than or equal to 0",
+		})
+	}
+
+	valid := true
+	for i, p := range cfg.Profiles {
+		if _, err := normalizeProfiles([]model.Profile{p}, cfg.VPN.ConnectionRetry); err != nil {
+			valid = false
+			origin := origins[fmt.Sprintf("profile[%d].name", i)]
+			problems = append(problems, Problem{Origin: origin, Message: err.Error()})
+		}
+	}
+	if valid {
+		if _, err := normalizeProfiles(cfg.Profiles, cfg.VPN.ConnectionRetry); err != nil {
+			problems = append(problems, Problem{Origin: origins["profile[0].name"], Message: err.Error()})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useLayers points the system and user configuration at files in a temporary
+// directory with the given contents ("" leaves a file out) and clears --set flags.
+func useLayers(t *testing.T, system, user string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	origSystem, origUser, origFlags, origLoaded := SystemConfigPath, UserConfigPath, flagOverrides, loaded
+	SystemConfigPath = filepath.Join(dir, "system.toml")
+	UserConfigPath = filepath.Join(dir, "user", "config.toml")
+	flagOverrides = nil
+	t.Cleanup(func() {
+		SystemConfigPath, UserConfigPath, flagOverrides, loaded = origSystem, origUser, origFlags, origLoaded
+	})
+	if system != "" {
+		require.NoError(t, os.WriteFile(SystemConfigPath, []byte(system), 0644))
+	}
+	if user != "" {
+		require.NoError(t, os.MkdirAll(filepath.Dir(UserConfigPath), 0700))
+		require.NoError(t, os.WriteFile(UserConfigPath, []byte(user), 0600))
+	}
+	return SystemConfigPath, UserConfigPath
+}
+
+func TestLoadConfig_LayerPrecedence(t *testing.T) {
+	system, user := useLayers(t,
+		"[daemon]\npoll_interval = \"10s\"\nauth_failure_limit = 5\n",
+		"# mine\n[daemon]\npoll_interval = \"20s\"\n")
+	t.Setenv("VPNCTL_DAEMON_AUTH_FAILURE_LIMIT", "7")
+	_, err := ParseFlags([]string{"--set", "logger.level=2", "status"})
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "20s", cfg.Daemon.PollInterval)
+	assert.Equal(t, 7, cfg.Daemon.AuthFailureLimit)
+	assert.Equal(t, 2, cfg.Logger.LoggerLevel)
+
+	assert.Equal(t, Origin{Source: user, Line: 3}, OriginOf("daemon.poll_interval"))
+	assert.Equal(t, "env VPNCTL_DAEMON_AUTH_FAILURE_LIMIT", OriginOf("daemon.auth_failure_limit").String())
+	assert.Equal(t, OriginFlag, OriginOf("logger.level").Source)
+	assert.Equal(t, OriginEmbedded, OriginOf("sqlite.path").String())
+	assert.Equal(t, []string{OriginEmbedded, system, user, OriginEnv, OriginFlag}, loaded.sources)
+}
+
+func TestLoadConfig_ArraysReplaced(t *testing.T) {
+	_, user := useLayers(t, "", "[[profile]]\nname = \"corp\"\nhost = \"vpn.example.com\"\n")
+
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	require.Len(t, cfg.Profiles, 1, "a layer's [[profile]] tables replace the lower ones")
+	assert.Equal(t, "corp", cfg.Profiles[0].Name)
+	assert.Equal(t, Origin{Source: user, Line: 3}, OriginOf("profile[0].host"))
+	assert.Equal(t, Origin{}, OriginOf("profile[1].host"))
+}
+
+func TestLoadConfig_BadEnvValue(t *testing.T) {
+	useLayers(t, "", "")
+	t.Setenv("VPNCTL_DAEMON_AUTH_FAILURE_LIMIT", "many")
+
+	_, err := LoadConfig("")
+	assert.ErrorContains(t, err, "VPNCTL_DAEMON_AUTH_FAILURE_LIMIT")
+}
+
+func TestParseFlags(t *testing.T) {
+	useLayers(t, "", "")
+	args, err := ParseFlags([]string{"--set=vpn.agent_wait=5s", "--set", "logger.level=1", "connect", "--set", "x"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"connect", "--set", "x"}, args)
+	assert.Equal(t, []string{"vpn.agent_wait=5s", "logger.level=1"}, flagOverrides)
+
+	_, err = ParseFlags([]string{"--set", "novalue"})
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	_, user := useLayers(t, "", strings.Join([]string{
+		"[daemon]",
+		"poll_interval = \"soon\"",
+		"pol_interval = \"10s\"",
+		"",
+		"[[profile]]",
+		"name = \"corp\"",
+		"host = \"vpn.example.com\"",
+		"mfa = \"sms\"",
+	}, "\n"))
+
+	var got []string
+	for _, p := range Validate("") {
+		got = append(got, p.Origin.String())
+	}
+	assert.Equal(t, []string{user + ":2", user + ":3", user + ":6"}, got)
+}
+
+func TestValidate_SyntaxError(t *testing.T) {
+	_, user := useLayers(t, "", "[vpn]\nconnection_retry = \"three\"\n")
+
+	problems := Validate("")
+	require.Len(t, problems, 1)
+	assert.Equal(t, Origin{Source: user, Line: 2}, problems[0].Origin)
+
+	file := filepath.Join(t.TempDir(), "candidate.toml")
+	require.NoError(t, os.WriteFile(file, []byte("[vpn]\nagent_wait = \n"), 0644))
+	problems = Validate(file)
+	require.Len(t, problems, 1, "only the given file is checked")
+	assert.Equal(t, Origin{Source: file, Line: 2}, problems[0].Origin)
+}
+
+func TestSetUserValue(t *testing.T) {
+	_, user := useLayers(t, "", "# my settings\n[daemon]\npoll_interval = \"20s\" # fast\n")
+	_, err := LoadConfig("")
+	require.NoError(t, err)
+
+	_, err = SetUserValue("daemon.poll_interval", "45s")
+	require.NoError(t, err)
+	_, err = SetUserValue("daemon.auth_failure_limit", "4")
+	require.NoError(t, err)
+	_, err = SetUserValue("logger.level", "2")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(user)
+	require.NoError(t, err)
+	assert.Equal(t, "# my settings\n[daemon]\nauth_failure_limit = 4\npoll_interval = \"45s\"\n\n[logger]\nlevel = 2\n", string(data))
+
+	_, err = SetUserValue("daemon.auth_failure_limit", "four")
+	assert.ErrorContains(t, err, "must be an integer")
+	_, err = SetUserValue("daemon.nope", "1")
+	assert.ErrorContains(t, err, "unknown configuration key")
+	_, err = SetUserValue("profile[0].host", "x")
+	assert.Error(t, err)
+}
+
+func TestKeyLines(t *testing.T) {
+	lines := keyLines([]byte(strings.Join([]string{
+		"top = 1",
+		"[[profile]]",
+		"name = \"a\"",
+		"[[profile.prompt]]",
+		"pattern = \"x\"",
+		"[[profile]]",
+		"name = \"b\"",
+		"[[profile.prompt]]",
+		"pattern = \"y\"",
+		"[profile.retry]",
+		"max_attempts = 2",
+	}, "\n")))
+
+	assert.Equal(t, 1, lines["top"])
+	assert.Equal(t, 3, lines["profile[0].name"])
+	assert.Equal(t, 5, lines["profile[0].prompt[0].pattern"])
+	assert.Equal(t, 9, lines["profile[1].prompt[0].pattern"])
+	assert.Equal(t, 11, lines["profile[1].retry.max_attempts"])
+	assert.Equal(t, 3, lines["profile.name"], "the first occurrence without indices")
+}
+
+func TestSetUserProfiles(t *testing.T) {
+	_, user := useLayers(t, "", "# mine\n[daemon]\npoll_interval = \"20s\"\n\n"+
+		"[[profile]]\nname = \"corp\"\nhost = \"vpn.example.com\"\n[[profile.prompt]]\npattern = \"x\"\nanswer = \"username\"\n\n"+
+		"[logger]\nlevel = 2\n")
+	_, err := LoadConfig("")
+	require.NoError(t, err)
+
+	doc, err := ProfileTables()
+	require.NoError(t, err)
+	tables, err := ParseProfiles(doc)
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "corp", tables[0]["name"])
+
+	tables = append(tables, map[string]any{"name": "lab", "host": "lab.example.com", "backend": "openconnect"})
+	_, err = SetUserProfiles(tables)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(user)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "# mine\n[daemon]\npoll_interval = \"20s\"\n\n[logger]\nlevel = 2\n\n[[profile]]\n"), string(data))
+
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	require.Len(t, cfg.Profiles, 2)
+	assert.Equal(t, "corp", cfg.Profiles[0].Name)
+	assert.Equal(t, "x", cfg.Profiles[0].Prompts[0].Pattern)
+	assert.Equal(t, "lab", cfg.Profiles[1].Name)
+	assert.Equal(t, 2, cfg.Logger.LoggerLevel)
+}
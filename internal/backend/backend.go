@@ -0,0 +1,90 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+// DefaultBackend is used for profiles that do not set `backend`.
+const DefaultBackend = "cisco"
+
+// ErrAgentLocked is returned by Connect when the VPN agent refuses new connections
+// until it is restarted (Cisco reports this as "Connect capability is unavailable").
+var ErrAgentLocked = errors.New("vpn agent is locked")
+
+// Backend drives one kind of VPN client. The rest of vpnctl (history, retries, hooks)
+// only talks to this interface, so every driver behaves the same from the CLI's point of view.
+type Backend interface {
+	// Name returns the identifier used in the profile's `backend` field.
+	Name() string
+	// Connect brings the tunnel for the profile up using the given credential.
+	Connect(ctx context.Context, profile *model.Profile, credential *model.CREDENTIAL_FOR_LOGIN) error
+	// Disconnect tears down whatever tunnel the backend currently owns.
+	Disconnect(ctx context.Context) error
+	// Status reports the current tunnel state.
+	Status(ctx context.Context) (*model.VPNState, error)
+	// Capabilities describes the optional features the backend supports.
+	Capabilities() Capabilities
+}
+
+// Capabilities lists optional features a backend may support.
+type Capabilities struct {
+	GUI         bool // has a desktop client that can be launched or killed
+	Stats       bool // Status reports transfer statistics
+	Credentials bool // Connect needs the stored username/password
+}
+
+var (
+	mu      sync.RWMutex
+	drivers = map[string]func() Backend{}
+)
+
+// Register makes a backend available under the given name.
+// Drivers register themselves from init.
+func Register(name string, factory func() Backend) {
+	mu.Lock()
+	defer mu.Unlock()
+	drivers[name] = factory
+}
+
+// Get returns a new instance of the named backend.
+func Get(name string) (Backend, error) {
+	mu.RLock()
+	factory, ok := drivers[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown VPN backend %q, available backends: %s", name, strings.Join(Names(), ", "))
+	}
+	return factory(), nil
+}
+
+// ForProfile returns the backend configured for the profile, falling back to DefaultBackend.
+func ForProfile(profile *model.Profile) (Backend, error) {
+	if profile == nil || profile.Backend == "" {
+		return Get(DefaultBackend)
+	}
+	return Get(profile.Backend)
+}
+
+// Names returns the registered backend names in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
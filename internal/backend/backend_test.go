@@ -0,0 +1,40 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	b, err := Get("cisco")
+	assert.NoError(t, err)
+	assert.Equal(t, "cisco", b.Name())
+
+	_, err = Get("nope")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "available backends: cisco, openconnect, openvpn, wireguard")
+}
+
+func TestForProfile(t *testing.T) {
+	b, err := ForProfile(&model.Profile{Name: "intra"})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultBackend, b.Name())
+
+	_, err = ForProfile(&model.Profile{Name: "lab", Backend: "nope"})
+	assert.Error(t, err)
+}
+
+func TestAnswerFor(t *testing.T) {
+	cred := &model.CREDENTIAL_FOR_LOGIN{Username: "user", Password: "pass", YFlag: "yflag", Push: "push"}
+	assert.Equal(t, "user", answerFor("username", cred))
+	assert.Equal(t, "pass", answerFor("password", cred))
+	assert.Equal(t, "yflag", answerFor("yflag", cred))
+	assert.Equal(t, "push", answerFor("push", cred))
+	assert.Equal(t, "accept", answerFor("accept", cred))
+
+	cred.SecondFactor = func() string { return "123456" }
+	assert.Equal(t, "123456", answerFor("totp", cred))
+	assert.Equal(t, "123456", answerFor("push", cred))
+}
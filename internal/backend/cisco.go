@@ -0,0 +1,322 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// ciscoGUIProcess returns the process name of the Cisco desktop client: the app
+// name on macOS ("Cisco Secure Client"), the binary name (vpnui, csc_ui) elsewhere.
+func ciscoGUIProcess() string {
+	name := filepath.Base(strings.TrimRight(config.VPN_GUI_PATH, `/\`))
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".app"), ".exe")
+	if name == "" || name == "." {
+		return "Cisco Secure Client"
+	}
+	return name
+}
+
+// GUI is implemented by backends that ship a desktop client.
+type GUI interface {
+	LaunchGUI() error
+	KillGUI() error
+}
+
+// Cisco drives the Cisco Secure Client (AnyConnect) `vpn` command line tool.
+type Cisco struct{}
+
+func init() {
+	Register("cisco", func() Backend { return &Cisco{} })
+}
+
+func (c *Cisco) Name() string { return "cisco" }
+
+func (c *Cisco) Capabilities() Capabilities {
+	return Capabilities{GUI: true, Credentials: true}
+}
+
+// Status runs `vpn status` and, while connected, `vpn stats`, and parses both
+// into a VPNState.
+func (c *Cisco) Status(ctx context.Context) (*model.VPNState, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, config.VPN_BINARY_PATH, "status").CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return &model.VPNState{State: model.StateUnknown}, fmt.Errorf("vpn status timed out")
+	}
+	if err != nil {
+		return &model.VPNState{State: model.StateUnknown, Raw: string(output)}, fmt.Errorf("retrieving VPN status: %w", err)
+	}
+
+	var stats []byte
+	if state := parseCiscoStatus(string(output), "", time.Now()); state.State == model.StateConnected {
+		// stats is best effort, the state is already known
+		stats, _ = exec.CommandContext(ctx, config.VPN_BINARY_PATH, "stats").CombinedOutput()
+	}
+	return parseCiscoStatus(string(output), string(stats), time.Now()), nil
+}
+
+// Connect kills stale GUI clients, then runs `vpn connect <host> -s` and answers
+// its prompts as they appear using the profile's prompt rules.
+// It returns ErrAgentLocked when the agent reports "Connect capability is unavailable"
+// and ErrUnexpectedPrompt when the client asks something no rule answers.
+func (c *Cisco) Connect(ctx context.Context, profile *model.Profile, credential *model.CREDENTIAL_FOR_LOGIN) error {
+	if err := c.KillClients(); err != nil {
+		logger.Errorf("failed to kill Cisco processes before reconnect: %v", err)
+	}
+
+	rules, err := compilePromptRules(profile.Prompts)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Running VPN command for host %v", profile.Host)
+	session := &expectSession{prefix: "VPN stdout", rules: rules, credential: credential}
+	stdoutLines, err := session.run(ctx, exec.CommandContext(ctx, config.VPN_BINARY_PATH, "connect", profile.Host, "-s"))
+
+	for _, line := range stdoutLines {
+		if strings.Contains(line, "Connect capability is unavailable") {
+			return ErrAgentLocked
+		}
+	}
+	return ciscoConnectError(stdoutLines, err)
+}
+
+// ciscoConnectError adds the client's error notices to the connect error so failures
+// can be classified. The CLI may exit 0 after a failed login, so the notices alone
+// are an error too.
+func ciscoConnectError(lines []string, err error) error {
+	var notices []string
+	for _, line := range lines {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), ">>"))
+		lower := strings.ToLower(line)
+		if strings.HasPrefix(lower, "error:") || strings.Contains(lower, "login failed") {
+			notices = append(notices, line)
+		}
+	}
+	if len(notices) == 0 {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.Join(notices, "; "))
+	}
+	return fmt.Errorf("vpn connect: %s", strings.Join(notices, "; "))
+}
+
+// Disconnect runs `vpn disconnect` and kills the Cisco Secure Client GUI.
+func (c *Cisco) Disconnect(ctx context.Context) error {
+	logger.Infof("Attempting to disconnect VPN...")
+	if err := exec.CommandContext(ctx, config.VPN_BINARY_PATH, "disconnect").Run(); err != nil {
+		return fmt.Errorf("vpn disconnect: %w", err)
+	}
+	logger.Infof("VPN disconnected")
+	exec.Command("pkill", "-x", ciscoGUIProcess()).Run()
+	return nil
+}
+
+// ForceDisconnect disconnects the VPN, kills the GUI and interrupts every
+// remaining `vpn` process except vpnagentd.
+func (c *Cisco) ForceDisconnect(ctx context.Context) error {
+	if err := c.Disconnect(ctx); err != nil {
+		logger.Errorf("%v", err)
+	}
+	logger.Infof("Cisco Secure Client UI process killed")
+
+	pids, err := getPIDs(ciscoCLIProcess)
+	if err != nil {
+		return fmt.Errorf("getting VPN PIDs: %w", err)
+	}
+
+	for _, pid := range pids {
+		// Use `ps -p <pid> -o command=` to get the full process path/command
+		out, err := exec.Command("ps", "-p", fmt.Sprintf("%d", pid), "-o", "command=").Output()
+		if err != nil {
+			logger.Errorf("could not inspect process %d: %v", pid, err)
+			continue
+		}
+
+		cmdline := strings.TrimSpace(string(out))
+		if strings.Contains(cmdline, "vpnagentd") {
+			logger.Infof("Skipping vpnagentd process (PID %d)", pid)
+			continue
+		}
+
+		logger.Infof("Killing VPN process with PID %d", pid)
+
+		process, err := os.FindProcess(pid)
+		if err != nil {
+			logger.Errorf("finding process %d: %v", pid, err)
+			continue
+		}
+
+		if err := process.Signal(os.Interrupt); err != nil {
+			logger.Errorf("killing VPN process %d: %v", pid, err)
+		}
+	}
+
+	logger.Infof("VPN disconnected and related processes (excluding vpnagentd) killed")
+	return nil
+}
+
+// LaunchGUI starts the Cisco Secure Client GUI application: through `open` for the
+// macOS .app bundle, by running the vpnui/csc_ui binary elsewhere.
+func (c *Cisco) LaunchGUI() error {
+	if config.VPN_GUI_PATH == "" {
+		return fmt.Errorf("no Cisco Secure Client GUI found, set [vpn] gui_path")
+	}
+	logger.Infof("Launching Cisco Secure Client GUI...")
+	cmd := exec.Command(config.VPN_GUI_PATH)
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("open", config.VPN_GUI_PATH)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("launching Cisco Secure Client GUI: %w", err)
+	}
+	go cmd.Wait() // the GUI keeps running after vpnctl exits
+	logger.Infof("Cisco GUI launched")
+	return nil
+}
+
+// KillGUI kills the Cisco Secure Client GUI and interrupts the `vpn` processes.
+func (c *Cisco) KillGUI() error {
+	logger.Infof("Killing Cisco Secure Client GUI...")
+	exec.Command("pkill", "-x", ciscoGUIProcess()).Run()
+	logger.Infof("Cisco GUI killed")
+
+	pids, err := getPIDs(ciscoCLIProcess)
+	if err != nil {
+		return fmt.Errorf("getting VPN PIDs: %w", err)
+	}
+	for _, pid := range pids {
+		process, err := os.FindProcess(pid)
+		if err != nil {
+			logger.Errorf("finding process %d: %v", pid, err)
+			continue
+		}
+		logger.Infof("Killing VPN process with PID %d", pid)
+		if err := process.Signal(os.Interrupt); err != nil { // Use SIGINT to gracefully stop
+			logger.Errorf("killing VPN process %d: %v", pid, err)
+		}
+	}
+	logger.Infof("VPN processes killed")
+	return nil
+}
+
+// KillClients force-kills the Cisco Secure Client GUI so the CLI can take the agent.
+// vpnagentd is never touched.
+func (c *Cisco) KillClients() error {
+	processes := []string{ciscoGUIProcess()} // Do NOT include vpnagentd
+	var killErrors []string
+
+	for _, name := range processes {
+		output, err := exec.Command("pgrep", "-f", name).Output()
+		if err != nil {
+			logger.Warningf("failed to find process: %s", name)
+			continue
+		}
+
+		for _, pid := range strings.Fields(string(output)) {
+			if err := exec.Command("kill", "-9", pid).Run(); err != nil {
+				msg := fmt.Sprintf("failed to kill PID %v for %v: %v", pid, name, err)
+				logger.Errorf("%s", msg)
+				killErrors = append(killErrors, msg)
+			} else {
+				logger.Infof("Killed %v (PID %v)", name, pid)
+			}
+		}
+	}
+
+	time.Sleep(2 * time.Second)
+
+	if len(killErrors) > 0 {
+		return fmt.Errorf("not all processes could be killed:\n%v", strings.Join(killErrors, "\n"))
+	}
+	return nil
+}
+
+// answerFor resolves the answer of a prompt rule.
+// The keywords username, password, yflag and push or totp (the second password)
+// are taken from the credential, anything else is sent to the VPN command verbatim.
+func answerFor(answer string, credential *model.CREDENTIAL_FOR_LOGIN) string {
+	switch answer {
+	case "username":
+		return credential.Username
+	case "password":
+		return credential.Password.Reveal()
+	case "yflag":
+		return credential.YFlag
+	case "push", "totp":
+		return credential.SecondPassword()
+	default:
+		return answer
+	}
+}
+
+// ciscoVersionPattern matches the banner every `vpn` command prints first,
+// e.g. "Cisco Secure Client (version 5.0.01242) .".
+var ciscoVersionPattern = regexp.MustCompile(`\(version ([^)\s]+)\)`)
+
+// CiscoVersion runs `vpn status` with the given binary and returns the client
+// version from its banner.
+func CiscoVersion(ctx context.Context, binary string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, binary, "status").CombinedOutput()
+	if m := ciscoVersionPattern.FindStringSubmatch(string(output)); m != nil {
+		return m[1], nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("running %s status: %w", binary, err)
+	}
+	return "", fmt.Errorf("no version in the output of %s status", binary)
+}
+
+// getPIDs retrieves process IDs for an exact process name using `pgrep -x`, so
+// vpnctl, vpnagentd or an openvpn process are never matched. No matching process
+// is not an error.
+func getPIDs(name string) ([]int, error) {
+	out, err := exec.Command("pgrep", "-x", name).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parsePIDs(string(out)), nil
+}
+
+// parsePIDs parses pgrep output, skipping the current process.
+func parsePIDs(out string) []int {
+	var pids []int
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		pid, err := strconv.Atoi(line)
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids
+}
@@ -0,0 +1,175 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// ciscoCLIProcess is the process name of the Cisco `vpn` command line client.
+const ciscoCLIProcess = "vpn"
+
+// RecoverAgent clears a "Connect capability is unavailable" agent lock so the next
+// connect attempt can go through. It kills stale GUI and CLI clients (never vpnagentd
+// or vpnctl itself), restarts vpnagentd through the platform service manager when
+// [vpn] restart_agent is set, and waits until the agent accepts connections again.
+// Every step is reported as it runs.
+func (c *Cisco) RecoverAgent(ctx context.Context) error {
+	type step struct {
+		name string
+		run  func(context.Context) error
+	}
+	steps := []step{{"kill stale Cisco Secure Client GUI and CLI processes", killStaleCiscoClients}}
+	if config.VPN_RESTART_AGENT {
+		steps = append(steps, step{"restart vpnagentd through the service manager", restartCiscoAgent})
+	}
+	steps = append(steps, step{fmt.Sprintf("wait for vpnagentd on %s", config.VPN_AGENT_ADDRESS), waitForCiscoAgent})
+
+	for i, s := range steps {
+		logger.Infof("Agent recovery step %d/%d: %s", i+1, len(steps), s.name)
+		if err := s.run(ctx); err != nil {
+			logger.Errorf("Agent recovery step %d/%d failed: %v", i+1, len(steps), err)
+			return fmt.Errorf("agent recovery step %q: %w", s.name, err)
+		}
+		logger.Infof("Agent recovery step %d/%d done", i+1, len(steps))
+	}
+	return nil
+}
+
+// killStaleCiscoClients kills the GUI (matched by its full command line) and any
+// `vpn` CLI process (matched by exact process name, so vpnctl is not hit) and waits
+// for them to exit. vpnagentd is never touched.
+func killStaleCiscoClients(ctx context.Context) error {
+	var pids []int
+	for _, args := range [][]string{{"-f", ciscoGUIProcess()}, {"-x", ciscoCLIProcess}} {
+		out, err := exec.CommandContext(ctx, "pgrep", args...).Output()
+		if err != nil {
+			continue // pgrep exits 1 when nothing matches
+		}
+		pids = append(pids, parsePIDs(string(out))...)
+	}
+
+	var killed []int
+	for _, pid := range pids {
+		cmdline, _ := exec.CommandContext(ctx, "ps", "-p", fmt.Sprint(pid), "-o", "command=").Output()
+		if strings.Contains(string(cmdline), "vpnagentd") {
+			logger.Infof("Skipping vpnagentd process (PID %d)", pid)
+			continue
+		}
+		process, err := os.FindProcess(pid)
+		if err == nil {
+			err = process.Kill()
+		}
+		if err != nil {
+			logger.Errorf("killing stale client PID %d: %v", pid, err)
+			continue
+		}
+		logger.Infof("Killed stale client %v (PID %d)", strings.TrimSpace(string(cmdline)), pid)
+		killed = append(killed, pid)
+	}
+	if len(killed) == 0 {
+		logger.Infof("No stale Cisco clients running")
+		return nil
+	}
+
+	for _, pid := range killed {
+		for i := 0; i < 50 && processAlive(pid); i++ {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if processAlive(pid) {
+			return fmt.Errorf("stale client PID %d did not exit", pid)
+		}
+	}
+	return nil
+}
+
+// restartCiscoAgent restarts vpnagentd through the platform service manager.
+func restartCiscoAgent(ctx context.Context) error {
+	name, args := agentRestartCommand()
+	if name == "" {
+		return fmt.Errorf("restarting vpnagentd is not supported on %s", runtime.GOOS)
+	}
+	logger.Infof("Running %v %v", name, strings.Join(args, " "))
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	printLines("agent", output)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// agentRestartCommand returns the service manager command restarting vpnagentd.
+func agentRestartCommand() (string, []string) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "launchctl", []string{"kickstart", "-k", "system/com.cisco.secureclient.vpnagentd"}
+	case "linux":
+		return "systemctl", []string{"restart", "vpnagentd"}
+	case "windows":
+		return "powershell", []string{"-NoProfile", "-Command", "Restart-Service csc_vpnagent"}
+	default:
+		return "", nil
+	}
+}
+
+// CiscoAgentPIDs returns the PIDs of running vpnagentd processes.
+func CiscoAgentPIDs(ctx context.Context) []int {
+	out, err := exec.CommandContext(ctx, "pgrep", "-x", "vpnagentd").Output()
+	if err != nil {
+		return nil // pgrep exits 1 when nothing matches
+	}
+	return parsePIDs(string(out))
+}
+
+// CiscoAgentReachable dials the agent's IPC endpoint ([vpn] agent_address) once.
+func CiscoAgentReachable(ctx context.Context) error {
+	if config.VPN_AGENT_ADDRESS == "" {
+		return fmt.Errorf("[vpn] agent_address is not set")
+	}
+	dialer := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", config.VPN_AGENT_ADDRESS)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
+
+// waitForCiscoAgent polls the agent's IPC endpoint until it accepts a connection
+// or [vpn] agent_wait expires.
+func waitForCiscoAgent(ctx context.Context) error {
+	if config.VPN_AGENT_ADDRESS == "" {
+		logger.Warningf("[vpn] agent_address is not set, not waiting for vpnagentd")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.VPN_AGENT_WAIT)
+	defer cancel()
+
+	var dialer net.Dialer
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", config.VPN_AGENT_ADDRESS)
+		if err == nil {
+			conn.Close()
+			logger.Infof("vpnagentd is accepting connections on %v", config.VPN_AGENT_ADDRESS)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("vpnagentd did not come back on %s within %s: %w", config.VPN_AGENT_ADDRESS, config.VPN_AGENT_WAIT, err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
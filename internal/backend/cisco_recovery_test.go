@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func setAgentAddress(t *testing.T, address string, wait time.Duration) {
+	t.Helper()
+	logger.InitLogger(false, "")
+	origAddress, origWait := config.VPN_AGENT_ADDRESS, config.VPN_AGENT_WAIT
+	config.VPN_AGENT_ADDRESS, config.VPN_AGENT_WAIT = address, wait
+	t.Cleanup(func() { config.VPN_AGENT_ADDRESS, config.VPN_AGENT_WAIT = origAddress, origWait })
+}
+
+func TestWaitForCiscoAgent_ComesBack(t *testing.T) {
+	// reserve a port, then bring the "agent" up on it a little later
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := l.Addr().String()
+	l.Close()
+	setAgentAddress(t, address, 5*time.Second)
+
+	go func() {
+		time.Sleep(700 * time.Millisecond)
+		if agent, err := net.Listen("tcp", address); err == nil {
+			t.Cleanup(func() { agent.Close() })
+			agent.Accept()
+		}
+	}()
+	assert.NoError(t, waitForCiscoAgent(context.Background()))
+}
+
+func TestWaitForCiscoAgent_TimesOut(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := l.Addr().String()
+	l.Close()
+	setAgentAddress(t, address, 600*time.Millisecond)
+
+	err = waitForCiscoAgent(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "did not come back")
+}
+
+func TestParsePIDs_SkipsSelf(t *testing.T) {
+	out := fmt.Sprintf("101\n%d\n202\n", os.Getpid())
+	assert.Equal(t, []int{101, 202}, parsePIDs(out))
+}
+
+func TestGetPIDs_MatchesOnlyTheCiscoBinary(t *testing.T) {
+	dir := t.TempDir()
+	start := func(name string) int {
+		bin := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\nsleep 30\n"), 0755))
+		cmd := exec.Command(bin)
+		assert.NoError(t, cmd.Start())
+		t.Cleanup(func() { cmd.Process.Kill(); cmd.Wait() })
+		return cmd.Process.Pid
+	}
+	cisco, client := start("vpn"), start("vpnctl")
+	time.Sleep(100 * time.Millisecond)
+
+	pids, err := getPIDs(ciscoCLIProcess)
+	assert.NoError(t, err)
+	assert.Contains(t, pids, cisco)
+	assert.NotContains(t, pids, client)
+
+	pids, err = getPIDs("no-such-process")
+	assert.NoError(t, err)
+	assert.Empty(t, pids)
+}
@@ -0,0 +1,110 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+var (
+	ciscoConnectedTo = regexp.MustCompile(`(?i)^connected to (.+?)\.?$`)
+	ciscoDuration    = regexp.MustCompile(`(?:(\d+)\s+days?\s+)?(\d+):(\d{2}):(\d{2})`)
+)
+
+// parseCiscoStatus turns the output of `vpn status` and, when connected, `vpn stats`
+// into a VPNState. The status output looks like
+//
+//	>> state: Connected
+//	>> notice: Connected to vpn.example.com.
+//
+// and the last reported state wins. Stats lines are "Key: Value" pairs; the client
+// address, server and session duration are taken from there.
+func parseCiscoStatus(status, stats string, now time.Time) *model.VPNState {
+	state := &model.VPNState{State: model.StateUnknown, Raw: status}
+
+	for _, line := range strings.Split(status, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), ">>"))
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "state":
+			state.State = ciscoState(value)
+		case "notice", "warning", "error":
+			if value == "" {
+				continue
+			}
+			state.Notices = append(state.Notices, value)
+			if m := ciscoConnectedTo.FindStringSubmatch(value); m != nil {
+				state.Host = m[1]
+			}
+		}
+	}
+
+	for _, line := range strings.Split(stats, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(key, "client address"), strings.HasPrefix(key, "client (ipv4)"):
+			if state.ClientAddress == "" {
+				state.ClientAddress = value
+			}
+		case strings.HasPrefix(key, "server address"), key == "server":
+			if state.Host == "" {
+				state.Host = value
+			}
+		case key == "duration":
+			if d, ok := parseCiscoDuration(value); ok {
+				since := now.Add(-d).Truncate(time.Second)
+				state.ConnectedSince = &since
+			}
+		case key == "bytes received":
+			state.RxBytes, _ = strconv.ParseInt(value, 10, 64)
+		case key == "bytes sent":
+			state.TxBytes, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return state
+}
+
+// ciscoState maps the Cisco state names onto the model states.
+func ciscoState(value string) string {
+	switch strings.ToLower(strings.TrimSuffix(value, ".")) {
+	case "connected":
+		return model.StateConnected
+	case "disconnected":
+		return model.StateDisconnected
+	case "connecting", "reconnecting", "disconnecting":
+		return model.StateConnecting
+	default:
+		return model.StateUnknown
+	}
+}
+
+// parseCiscoDuration parses durations like "01:02:03" or "2 days 01:02:03".
+func parseCiscoDuration(value string) (time.Duration, bool) {
+	m := ciscoDuration.FindStringSubmatch(value)
+	if m == nil {
+		return 0, false
+	}
+	days, _ := strconv.Atoi(m[1])
+	hours, _ := strconv.Atoi(m[2])
+	minutes, _ := strconv.Atoi(m[3])
+	seconds, _ := strconv.Atoi(m[4])
+	return time.Duration(days)*24*time.Hour + time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, true
+}
@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const ciscoStatusConnected = `Cisco Secure Client (version 5.0.01242) .
+
+Copyright (c) 2004 - 2022 Cisco Systems, Inc.  All Rights Reserved.
+
+
+  >> state: Connecting
+  >> notice: Contacting vpn.example.com.
+  >> state: Connected
+  >> notice: Connected to vpn.example.com.
+  >> registered with local VPN subsystem.
+VPN> `
+
+const ciscoStats = `[ Connection Information ]
+
+    Tunnel Mode (IPv4):         Tunnel All Traffic
+    Duration:                   01:02:03
+    Session Disconnect:         None
+
+[ Address Information ]
+
+    Client Address (IPv4):      10.1.2.3
+    Server Address:             203.0.113.10
+
+[ Bytes ]
+
+    Bytes Sent:                 2048
+    Bytes Received:             4096
+`
+
+func TestParseCiscoStatus_Connected(t *testing.T) {
+	now := time.Date(2025, 6, 19, 12, 0, 0, 0, time.UTC)
+	state := parseCiscoStatus(ciscoStatusConnected, ciscoStats, now)
+
+	assert.Equal(t, model.StateConnected, state.State)
+	assert.Equal(t, "vpn.example.com", state.Host)
+	assert.Equal(t, "10.1.2.3", state.ClientAddress)
+	assert.Equal(t, now.Add(-(time.Hour + 2*time.Minute + 3*time.Second)), *state.ConnectedSince)
+	assert.Equal(t, []string{"Contacting vpn.example.com.", "Connected to vpn.example.com."}, state.Notices)
+	assert.Equal(t, int64(4096), state.RxBytes)
+	assert.Equal(t, int64(2048), state.TxBytes)
+}
+
+func TestParseCiscoStatus_States(t *testing.T) {
+	assert.Equal(t, model.StateDisconnected, parseCiscoStatus("  >> state: Disconnected\n", "", time.Now()).State)
+	assert.Equal(t, model.StateConnecting, parseCiscoStatus("  >> state: Reconnecting\n", "", time.Now()).State)
+	assert.Equal(t, model.StateUnknown, parseCiscoStatus("VPN> ", "", time.Now()).State)
+}
+
+func TestParseCiscoDuration(t *testing.T) {
+	d, ok := parseCiscoDuration("2 days 01:00:00")
+	assert.True(t, ok)
+	assert.Equal(t, 49*time.Hour, d)
+
+	_, ok = parseCiscoDuration("n/a")
+	assert.False(t, ok)
+}
+
+func TestCiscoVersion(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "vpn")
+	script := "#!/bin/sh\ncat <<'OUT'\n" + ciscoStatusConnected + "\nOUT\n"
+	assert.NoError(t, os.WriteFile(bin, []byte(script), 0755))
+
+	version, err := CiscoVersion(context.Background(), bin)
+	assert.NoError(t, err)
+	assert.Equal(t, "5.0.01242", version)
+
+	_, err = CiscoVersion(context.Background(), filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+var (
+	// promptIdleTimeout is how long output may stall on an unanswered prompt
+	// before it is reported as unexpected.
+	promptIdleTimeout = 3 * time.Second
+	// maxPromptRepeats bounds how often one rule may answer; the CLI asking for the
+	// password again means the credential was rejected.
+	maxPromptRepeats = 2
+)
+
+// ErrUnexpectedPrompt is returned when the VPN client waits on a prompt no rule matches.
+var ErrUnexpectedPrompt = errors.New("unexpected prompt from VPN client")
+
+// promptRule is a compiled model.PromptRule.
+type promptRule struct {
+	pattern *regexp.Regexp
+	answer  string
+}
+
+// compilePromptRules compiles the profile's prompt rules in order.
+func compilePromptRules(rules []model.PromptRule) ([]promptRule, error) {
+	compiled := make([]promptRule, 0, len(rules))
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt pattern %q: %w", rule.Pattern, err)
+		}
+		compiled = append(compiled, promptRule{pattern: re, answer: rule.Answer})
+	}
+	return compiled, nil
+}
+
+// expectSession answers the prompts of a running command over its stdin/stdout.
+// Prompts are the partial line the command is waiting on; the first matching rule
+// wins and its answer is written back followed by a newline. Complete lines are
+// echoed with the given prefix and returned for the caller to inspect.
+type expectSession struct {
+	prefix     string
+	rules      []promptRule
+	credential *model.CREDENTIAL_FOR_LOGIN
+}
+
+// run starts cmd and drives it until it exits. It fails with ErrUnexpectedPrompt
+// (including the prompt text) when the command waits on output no rule matches,
+// and with ErrAuthFailed when the same prompt keeps coming back.
+func (s *expectSession) run(ctx context.Context, cmd *exec.Cmd) ([]string, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("getting stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("getting stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("getting stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting VPN command: %w", err)
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.Errorf("%s stderr: %v", s.prefix, scanner.Text())
+		}
+	}()
+
+	chunks := make(chan string)
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, 4096)
+		for {
+			n, err := stdout.Read(buf)
+			if n > 0 {
+				chunks <- string(buf[:n])
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	lines, runErr := s.converse(ctx, stdin, chunks)
+	stdin.Close()
+	if runErr != nil {
+		cmd.Process.Kill()
+		for range chunks {
+			// drain so the reader goroutine can exit
+		}
+		cmd.Wait()
+		return lines, runErr
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return lines, fmt.Errorf("VPN command exited with error: %w", err)
+	}
+	return lines, nil
+}
+
+// converse consumes output chunks and answers prompts until the output ends.
+func (s *expectSession) converse(ctx context.Context, stdin io.Writer, chunks <-chan string) ([]string, error) {
+	var lines []string
+	var pending strings.Builder
+	answered := make(map[int]int)
+
+	emit := func(line string) {
+		fmt.Printf("[%s] %s\n", s.prefix, line)
+		lines = append(lines, line)
+	}
+
+	idle := time.NewTimer(promptIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return lines, ctx.Err()
+
+		case <-idle.C:
+			prompt := strings.TrimSpace(pending.String())
+			if looksLikePrompt(prompt) {
+				return lines, fmt.Errorf("%w: %q", ErrUnexpectedPrompt, prompt)
+			}
+			idle.Reset(promptIdleTimeout)
+
+		case chunk, ok := <-chunks:
+			if !ok {
+				if pending.Len() > 0 {
+					emit(pending.String())
+				}
+				return lines, nil
+			}
+			idle.Reset(promptIdleTimeout)
+
+			for {
+				i := strings.IndexByte(chunk, '\n')
+				if i < 0 {
+					pending.WriteString(chunk)
+					break
+				}
+				pending.WriteString(strings.TrimRight(chunk[:i], "\r"))
+				emit(pending.String())
+				pending.Reset()
+				chunk = chunk[i+1:]
+			}
+
+			prompt := pending.String()
+			idx, rule := s.match(prompt)
+			if rule == nil {
+				continue
+			}
+			answered[idx]++
+			if answered[idx] > maxPromptRepeats {
+				return lines, fmt.Errorf("%w: the VPN client asked %q again", ErrAuthFailed, strings.TrimSpace(prompt))
+			}
+			emit(prompt)
+			pending.Reset()
+			logger.Infof("answering prompt %q", strings.TrimSpace(prompt))
+			if _, err := io.WriteString(stdin, answerFor(rule.answer, s.credential)+"\n"); err != nil {
+				return lines, fmt.Errorf("answering prompt %q: %w", strings.TrimSpace(prompt), err)
+			}
+		}
+	}
+}
+
+// match returns the first rule matching the prompt.
+func (s *expectSession) match(prompt string) (int, *promptRule) {
+	if strings.TrimSpace(prompt) == "" {
+		return -1, nil
+	}
+	for i := range s.rules {
+		if s.rules[i].pattern.MatchString(prompt) {
+			return i, &s.rules[i]
+		}
+	}
+	return -1, nil
+}
+
+// looksLikePrompt reports whether stalled output is waiting for input.
+func looksLikePrompt(text string) bool {
+	return strings.HasSuffix(text, ":") || strings.HasSuffix(text, "?") || strings.HasSuffix(text, "]")
+}
@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// promptScript prints each prompt without a newline and records the answer it reads.
+const promptScript = `
+for p in "$@"; do printf '%s' "$p"; read -r ans; echo "$ans" >> "$ANSWERS"; done
+echo
+echo "  >> state: Connected"
+`
+
+func runPromptScript(t *testing.T, rules []model.PromptRule, prompts ...string) ([]string, string, error) {
+	t.Helper()
+	logger.InitLogger(false, "")
+	answers := filepath.Join(t.TempDir(), "answers.txt")
+	t.Setenv("ANSWERS", answers)
+
+	compiled, err := compilePromptRules(rules)
+	assert.NoError(t, err)
+	session := &expectSession{
+		prefix:     "test",
+		rules:      compiled,
+		credential: &model.CREDENTIAL_FOR_LOGIN{Username: "user", Password: "pass", YFlag: "y", Push: "push"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	lines, err := session.run(ctx, exec.CommandContext(ctx, "sh", append([]string{"-c", promptScript, "sh"}, prompts...)...))
+	data, _ := os.ReadFile(answers)
+	return lines, string(data), err
+}
+
+var ciscoRules = []model.PromptRule{
+	{Pattern: `(?i)username:`, Answer: "username"},
+	{Pattern: `(?i)second password:`, Answer: "push"},
+	{Pattern: `(?i)password:`, Answer: "password"},
+	{Pattern: `(?i)accept\?\s*\[y/n\]`, Answer: "yflag"},
+}
+
+func TestExpectSession_AnswersPromptsInAnyOrder(t *testing.T) {
+	lines, answers, err := runPromptScript(t, ciscoRules,
+		"Username: [jdoe] ", "Password: ", "Second Password: ", "accept? [y/n]: ")
+	assert.NoError(t, err)
+	assert.Equal(t, "user\npass\npush\ny\n", answers)
+	assert.Contains(t, lines, "  >> state: Connected")
+
+	_, answers, err = runPromptScript(t, ciscoRules, "accept? [y/n]: ", "Password: ", "Username: ")
+	assert.NoError(t, err)
+	assert.Equal(t, "y\npass\nuser\n", answers)
+}
+
+func TestExpectSession_UnexpectedPrompt(t *testing.T) {
+	orig := promptIdleTimeout
+	promptIdleTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { promptIdleTimeout = orig })
+
+	_, _, err := runPromptScript(t, ciscoRules, "Username: ", "Enter your PIN: ")
+	assert.True(t, errors.Is(err, ErrUnexpectedPrompt))
+	assert.Contains(t, err.Error(), `"Enter your PIN:"`)
+}
+
+func TestExpectSession_RepeatedPromptIsAuthFailure(t *testing.T) {
+	_, _, err := runPromptScript(t, ciscoRules, "Password: ", "Password: ", "Password: ")
+	assert.True(t, errors.Is(err, ErrAuthFailed))
+}
+
+func TestCompilePromptRules_Invalid(t *testing.T) {
+	_, err := compilePromptRules([]model.PromptRule{{Pattern: "(", Answer: "password"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,196 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// OpenConnect drives `openconnect` against AnyConnect-compatible gateways.
+// openconnect daemonizes itself after authentication; its PID is tracked in
+// the configured pid file so Disconnect and Status work from later invocations.
+type OpenConnect struct{}
+
+func init() {
+	Register("openconnect", func() Backend { return &OpenConnect{} })
+}
+
+func (o *OpenConnect) Name() string { return "openconnect" }
+
+func (o *OpenConnect) Capabilities() Capabilities {
+	return Capabilities{Credentials: true}
+}
+
+// Connect runs openconnect in the background and answers its login form on stdin:
+// the username, the password and, for push or totp MFA, the second password.
+// Nothing secret goes on the command line, where ps would show it.
+func (o *OpenConnect) Connect(ctx context.Context, profile *model.Profile, credential *model.CREDENTIAL_FOR_LOGIN) error {
+	pidFile, err := o.pidFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(pidFile), 0700); err != nil {
+		return fmt.Errorf("creating pid file directory: %w", err)
+	}
+
+	args := []string{
+		"--protocol=anyconnect",
+		"--background",
+		"--pid-file=" + pidFile,
+	}
+	if profile.AuthGroup != "" {
+		args = append(args, "--authgroup="+profile.AuthGroup)
+	}
+	args = append(args, profile.Host)
+
+	stdin := credential.Username + "\n" + credential.Password.Reveal() + "\n"
+	if profile.MFA == "push" || profile.MFA == "totp" {
+		stdin += credential.SecondPassword() + "\n"
+	}
+
+	// The daemonized openconnect keeps the stdout and stderr it inherited, so
+	// they go to a log file: a pipe would stay open and never let the command
+	// return.
+	logPath := o.logFile(pidFile)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("opening openconnect log: %w", err)
+	}
+	defer logFile.Close()
+
+	logger.Infof("Running openconnect against %v", profile.Host)
+	cmd := exec.CommandContext(ctx, config.OPENCONNECT_BINARY_PATH, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	err = cmd.Run()
+	if output, readErr := os.ReadFile(logPath); readErr == nil {
+		printLines("openconnect", output)
+	}
+	if err != nil {
+		return fmt.Errorf("openconnect exited with error: %w", err)
+	}
+
+	// openconnect writes the pid file once it has forked into the background
+	if _, err := o.waitForPID(pidFile, 5*time.Second); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Disconnect interrupts the daemonized openconnect process and removes the pid file.
+func (o *OpenConnect) Disconnect(ctx context.Context) error {
+	pidFile, err := o.pidFile()
+	if err != nil {
+		return err
+	}
+	pid, err := readPID(pidFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // nothing to disconnect
+		}
+		return err
+	}
+
+	if processAlive(pid) {
+		logger.Infof("Stopping openconnect (PID %d)", pid)
+		process, err := os.FindProcess(pid)
+		if err != nil {
+			return fmt.Errorf("finding openconnect process %d: %w", pid, err)
+		}
+		if err := process.Signal(os.Interrupt); err != nil {
+			return fmt.Errorf("stopping openconnect process %d: %w", pid, err)
+		}
+		for i := 0; i < 50 && processAlive(pid); i++ {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing pid file: %w", err)
+	}
+	return nil
+}
+
+// Status reports connected while the process from the pid file is alive.
+func (o *OpenConnect) Status(ctx context.Context) (*model.VPNState, error) {
+	pidFile, err := o.pidFile()
+	if err != nil {
+		return nil, err
+	}
+	pid, err := readPID(pidFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &model.VPNState{State: model.StateDisconnected, Raw: "openconnect is not running"}, nil
+		}
+		return &model.VPNState{State: model.StateUnknown}, err
+	}
+	if !processAlive(pid) {
+		return &model.VPNState{State: model.StateDisconnected, Raw: fmt.Sprintf("openconnect (PID %d) is not running, stale pid file", pid)}, nil
+	}
+	return &model.VPNState{State: model.StateConnected, Raw: fmt.Sprintf("openconnect is running (PID %d)", pid)}, nil
+}
+
+func (o *OpenConnect) pidFile() (string, error) {
+	return middleware.ExpandPath(config.OPENCONNECT_PID_FILE)
+}
+
+// logFile is where openconnect writes its output, next to the pid file.
+func (o *OpenConnect) logFile(pidFile string) string {
+	return strings.TrimSuffix(pidFile, filepath.Ext(pidFile)) + ".log"
+}
+
+// waitForPID polls the pid file until it holds a live process or the timeout expires.
+func (o *OpenConnect) waitForPID(pidFile string, timeout time.Duration) (int, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if pid, err := readPID(pidFile); err == nil && processAlive(pid) {
+			return pid, nil
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("openconnect did not stay in the background (no live PID in %s)", pidFile)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// readPID reads a process id from a pid file.
+func readPID(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %s: %w", path, err)
+	}
+	return pid, nil
+}
+
+// processAlive reports whether a process with the given id exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOpenConnectScript records its arguments and stdin, then leaves a
+// long-running child behind in the pid file like `openconnect --background` does.
+// The child keeps the inherited stdout and stderr open, as openconnect does.
+const fakeOpenConnectScript = `#!/bin/sh
+echo "$*" > "$FAKE_OC_ARGS"
+cat > "$FAKE_OC_STDIN"
+for arg in "$@"; do
+	case "$arg" in --pid-file=*) pidfile="${arg#--pid-file=}" ;; esac
+done
+echo "Connected as 192.0.2.10"
+sleep 30 &
+echo $! > "$pidfile"
+`
+
+func TestOpenConnect_Lifecycle(t *testing.T) {
+	logger.InitLogger(false, "")
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "openconnect")
+	assert.NoError(t, os.WriteFile(bin, []byte(fakeOpenConnectScript), 0755))
+	t.Setenv("FAKE_OC_ARGS", filepath.Join(dir, "args"))
+	t.Setenv("FAKE_OC_STDIN", filepath.Join(dir, "stdin"))
+
+	origBinary, origPid := config.OPENCONNECT_BINARY_PATH, config.OPENCONNECT_PID_FILE
+	config.OPENCONNECT_BINARY_PATH = bin
+	config.OPENCONNECT_PID_FILE = filepath.Join(dir, "run", "openconnect.pid")
+	defer func() { config.OPENCONNECT_BINARY_PATH, config.OPENCONNECT_PID_FILE = origBinary, origPid }()
+
+	oc := &OpenConnect{}
+	ctx := context.Background()
+
+	state, err := oc.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StateDisconnected, state.State)
+
+	profile := &model.Profile{Name: "lab", Host: "vpn.example.com", AuthGroup: "Engineering", MFA: "push"}
+	cred := &model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "s3cret", Push: "push"}
+	assert.NoError(t, oc.Connect(ctx, profile, cred))
+
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	assert.NotContains(t, string(args), "alice")
+	assert.Contains(t, string(args), "--authgroup=Engineering")
+	assert.Contains(t, string(args), "vpn.example.com")
+	assert.NotContains(t, string(args), "s3cret")
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin"))
+	assert.Equal(t, "alice\ns3cret\npush\n", string(stdin))
+	output, _ := os.ReadFile(filepath.Join(dir, "run", "openconnect.log"))
+	assert.Contains(t, string(output), "Connected as 192.0.2.10")
+
+	state, err = oc.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StateConnected, state.State)
+
+	assert.NoError(t, oc.Disconnect(ctx))
+	state, err = oc.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StateDisconnected, state.State)
+}
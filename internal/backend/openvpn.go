@@ -0,0 +1,343 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// ErrAuthFailed is returned by Connect when the server rejects the credential.
+var ErrAuthFailed = errors.New("authentication failed")
+
+// openvpnConnectTimeout bounds Connect when the caller's context has no deadline.
+const openvpnConnectTimeout = 2 * time.Minute
+
+// OpenVPN starts `openvpn` as a daemon with its management interface on a unix
+// socket and drives authentication and state through that socket instead of
+// scraping stdout. The profile's host is the path to the .ovpn config.
+type OpenVPN struct{}
+
+func init() {
+	Register("openvpn", func() Backend { return &OpenVPN{} })
+}
+
+func (o *OpenVPN) Name() string { return "openvpn" }
+
+func (o *OpenVPN) Capabilities() Capabilities {
+	return Capabilities{Credentials: true}
+}
+
+// Connect starts openvpn on hold, releases it over the management socket, answers
+// >PASSWORD: requests (including static-challenge and CRV1 dynamic challenges, which
+// use the credential's second password as response) and waits for >STATE:CONNECTED.
+func (o *OpenVPN) Connect(ctx context.Context, profile *model.Profile, credential *model.CREDENTIAL_FOR_LOGIN) error {
+	socket, pidFile, err := o.paths()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return fmt.Errorf("creating management socket directory: %w", err)
+	}
+	os.Remove(socket) // stale socket from a previous run
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, openvpnConnectTimeout)
+		defer cancel()
+	}
+
+	args := []string{
+		"--config", profile.Host,
+		"--daemon",
+		"--writepid", pidFile,
+		"--management", socket, "unix",
+		"--management-query-passwords",
+		"--management-hold",
+		"--auth-retry", "interact",
+		"--auth-nocache",
+	}
+	logger.Infof("Starting openvpn with config %v", profile.Host)
+	output, err := exec.CommandContext(ctx, config.OPENVPN_BINARY_PATH, args...).CombinedOutput()
+	printLines("openvpn", output)
+	if err != nil {
+		return fmt.Errorf("starting openvpn: %w", err)
+	}
+
+	mgmt, err := dialManagement(ctx, socket, true)
+	if err != nil {
+		return err
+	}
+	defer mgmt.Close()
+
+	if err := mgmt.send("state on"); err != nil {
+		return err
+	}
+
+	var pendingChallenge *crv1Challenge
+	for {
+		line, err := mgmt.readLine(ctx)
+		if err != nil {
+			return fmt.Errorf("reading openvpn management interface: %w", err)
+		}
+
+		switch {
+		case strings.HasPrefix(line, ">HOLD:"):
+			if err := mgmt.send("hold release"); err != nil {
+				return err
+			}
+
+		case strings.HasPrefix(line, ">PASSWORD:Verification Failed"):
+			challenge, ok := parseCRV1(line)
+			if !ok {
+				o.Disconnect(context.Background())
+				return ErrAuthFailed
+			}
+			logger.Infof("openvpn server asked for a second factor: %v", challenge.Text)
+			pendingChallenge = challenge
+
+		case strings.HasPrefix(line, ">PASSWORD:Need 'Auth'"):
+			username, password := credential.Username, credential.Password.Reveal()
+			if pendingChallenge != nil {
+				username = pendingChallenge.Username
+				password = fmt.Sprintf("CRV1::%s::%s", pendingChallenge.StateID, credential.SecondPassword())
+				pendingChallenge = nil
+			} else if strings.Contains(line, " SC:") {
+				password = staticChallengeResponse(credential.Password.Reveal(), credential.SecondPassword())
+			}
+			if err := mgmt.send(fmt.Sprintf("username \"Auth\" %s", quoteManagement(username))); err != nil {
+				return err
+			}
+			if err := mgmt.send(fmt.Sprintf("password \"Auth\" %s", quoteManagement(password))); err != nil {
+				return err
+			}
+
+		case strings.HasPrefix(line, ">STATE:"):
+			state := parseOpenVPNState(strings.TrimPrefix(line, ">STATE:"))
+			logger.Infof("openvpn state: %v", state)
+			switch state {
+			case "CONNECTED":
+				return nil
+			case "EXITING":
+				return fmt.Errorf("openvpn exited before the tunnel came up")
+			}
+
+		case strings.HasPrefix(line, ">FATAL:"):
+			return fmt.Errorf("openvpn: %s", strings.TrimPrefix(line, ">FATAL:"))
+
+		case strings.HasPrefix(line, "ERROR:"):
+			logger.Warningf("openvpn management: %v", line)
+		}
+	}
+}
+
+// Disconnect asks openvpn to exit through the management socket, falling back
+// to signalling the PID from the pid file.
+func (o *OpenVPN) Disconnect(ctx context.Context) error {
+	socket, pidFile, err := o.paths()
+	if err != nil {
+		return err
+	}
+
+	pid, pidErr := readPID(pidFile)
+	if mgmt, err := dialManagement(ctx, socket, false); err == nil {
+		err = mgmt.send("signal SIGTERM")
+		mgmt.Close()
+		if err != nil {
+			return err
+		}
+	} else if pidErr == nil && processAlive(pid) {
+		process, _ := os.FindProcess(pid)
+		if err := process.Signal(syscall.SIGTERM); err != nil {
+			return fmt.Errorf("stopping openvpn process %d: %w", pid, err)
+		}
+	} else {
+		return nil // nothing to disconnect
+	}
+
+	for i := 0; pidErr == nil && i < 50 && processAlive(pid); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	os.Remove(pidFile)
+	os.Remove(socket)
+	logger.Infof("openvpn stopped")
+	return nil
+}
+
+// Status asks the management interface for the current state.
+func (o *OpenVPN) Status(ctx context.Context) (*model.VPNState, error) {
+	socket, _, err := o.paths()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	mgmt, err := dialManagement(ctx, socket, false)
+	if err != nil {
+		return &model.VPNState{State: model.StateDisconnected, Raw: "openvpn is not running"}, nil
+	}
+	defer mgmt.Close()
+
+	if err := mgmt.send("state"); err != nil {
+		return &model.VPNState{State: model.StateUnknown}, err
+	}
+	for {
+		line, err := mgmt.readLine(ctx)
+		if err != nil {
+			return &model.VPNState{State: model.StateUnknown}, fmt.Errorf("reading openvpn state: %w", err)
+		}
+		if strings.HasPrefix(line, ">") || strings.HasPrefix(line, "SUCCESS:") || line == "END" {
+			continue
+		}
+		state := parseOpenVPNState(line)
+		if state == "" {
+			continue
+		}
+		vpnState := &model.VPNState{State: model.StateConnecting, Raw: "openvpn state: " + line}
+		fields := strings.Split(line, ",")
+		switch state {
+		case "CONNECTED":
+			vpnState.State = model.StateConnected
+			if since, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+				at := time.Unix(since, 0)
+				vpnState.ConnectedSince = &at
+			}
+			if len(fields) > 4 {
+				vpnState.ClientAddress = fields[3]
+				vpnState.Host = fields[4]
+			}
+		case "EXITING":
+			vpnState.State = model.StateDisconnected
+		}
+		return vpnState, nil
+	}
+}
+
+func (o *OpenVPN) paths() (socket string, pidFile string, err error) {
+	if socket, err = middleware.ExpandPath(config.OPENVPN_MANAGEMENT_SOCKET); err != nil {
+		return "", "", err
+	}
+	if pidFile, err = middleware.ExpandPath(config.OPENVPN_PID_FILE); err != nil {
+		return "", "", err
+	}
+	return socket, pidFile, nil
+}
+
+// managementConn is a line based client for the openvpn management interface.
+type managementConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialManagement connects to the management socket. With wait set it keeps
+// retrying until openvpn has created the socket or the context is done.
+func dialManagement(ctx context.Context, socket string, wait bool) (*managementConn, error) {
+	var dialer net.Dialer
+	for {
+		conn, err := dialer.DialContext(ctx, "unix", socket)
+		if err == nil {
+			return &managementConn{conn: conn, reader: bufio.NewReader(conn)}, nil
+		}
+		if !wait {
+			return nil, fmt.Errorf("connecting to openvpn management socket %s: %w", socket, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connecting to openvpn management socket %s: %w", socket, err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func (m *managementConn) send(command string) error {
+	if _, err := fmt.Fprintf(m.conn, "%s\n", command); err != nil {
+		return fmt.Errorf("writing to openvpn management interface: %w", err)
+	}
+	return nil
+}
+
+// readLine reads one line, honouring the context deadline.
+func (m *managementConn) readLine(ctx context.Context) (string, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		m.conn.SetReadDeadline(deadline)
+	}
+	line, err := m.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (m *managementConn) Close() error {
+	return m.conn.Close()
+}
+
+// crv1Challenge is a dynamic challenge sent by the server after the first password.
+type crv1Challenge struct {
+	StateID  string
+	Username string
+	Text     string
+}
+
+// parseCRV1 extracts a dynamic challenge from a line like
+// >PASSWORD:Verification Failed: 'Auth' ['CRV1:R,E:state_id:base64_username:text']
+func parseCRV1(line string) (*crv1Challenge, bool) {
+	start := strings.Index(line, "CRV1:")
+	if start < 0 {
+		return nil, false
+	}
+	body := strings.TrimSuffix(strings.TrimSuffix(line[start+len("CRV1:"):], "]"), "'")
+	parts := strings.SplitN(body, ":", 4)
+	if len(parts) != 4 {
+		return nil, false
+	}
+	username, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, false
+	}
+	return &crv1Challenge{StateID: parts[1], Username: string(username), Text: parts[3]}, true
+}
+
+// staticChallengeResponse builds the SCRV1 password for static-challenge configs.
+func staticChallengeResponse(password, response string) string {
+	return fmt.Sprintf("SCRV1:%s:%s",
+		base64.StdEncoding.EncodeToString([]byte(password)),
+		base64.StdEncoding.EncodeToString([]byte(response)))
+}
+
+// parseOpenVPNState returns the state name from a state line such as
+// 1700000000,CONNECTED,SUCCESS,10.8.0.2,203.0.113.1
+func parseOpenVPNState(line string) string {
+	fields := strings.Split(line, ",")
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// quoteManagement quotes a value for the management protocol.
+func quoteManagement(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
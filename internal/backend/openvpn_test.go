@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCRV1(t *testing.T) {
+	c, ok := parseCRV1(">PASSWORD:Verification Failed: 'Auth' ['CRV1:R,E:Om01u7Fh4LrGBS7uh0SWmzwabUiGiW6l:Y3Ix:Please enter token PIN']")
+	assert.True(t, ok)
+	assert.Equal(t, "Om01u7Fh4LrGBS7uh0SWmzwabUiGiW6l", c.StateID)
+	assert.Equal(t, "cr1", c.Username)
+	assert.Equal(t, "Please enter token PIN", c.Text)
+
+	_, ok = parseCRV1(">PASSWORD:Verification Failed: 'Auth'")
+	assert.False(t, ok)
+}
+
+func TestStaticChallengeResponse(t *testing.T) {
+	assert.Equal(t, "SCRV1:cGFzcw==:MTIzNDU2", staticChallengeResponse("pass", "123456"))
+}
+
+func TestQuoteManagement(t *testing.T) {
+	assert.Equal(t, `"p\"a\\ss"`, quoteManagement(`p"a\ss`))
+}
+
+// TestOpenVPN_Connect plays the openvpn side of the management protocol:
+// hold, a static-challenge password request, then CONNECTED.
+func TestOpenVPN_Connect(t *testing.T) {
+	logger.InitLogger(false, "")
+	dir, err := os.MkdirTemp("", "ovpn") // short path, unix socket names are length limited
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "mgmt.sock")
+	started := filepath.Join(dir, "started")
+	bin := filepath.Join(dir, "openvpn")
+	assert.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\ntouch \""+started+"\"\n"), 0755))
+
+	origBinary, origSocket, origPid := config.OPENVPN_BINARY_PATH, config.OPENVPN_MANAGEMENT_SOCKET, config.OPENVPN_PID_FILE
+	config.OPENVPN_BINARY_PATH = bin
+	config.OPENVPN_MANAGEMENT_SOCKET = socket
+	config.OPENVPN_PID_FILE = filepath.Join(dir, "openvpn.pid")
+	defer func() {
+		config.OPENVPN_BINARY_PATH, config.OPENVPN_MANAGEMENT_SOCKET, config.OPENVPN_PID_FILE = origBinary, origSocket, origPid
+	}()
+
+	received := make(chan []string, 1)
+	go func() {
+		for {
+			if _, err := os.Stat(started); err == nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		ln, err := net.Listen("unix", socket)
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		var got []string
+		read := func() string {
+			line, _ := r.ReadString('\n')
+			line = strings.TrimSpace(line)
+			got = append(got, line)
+			return line
+		}
+		conn.Write([]byte(">INFO:OpenVPN Management Interface Version 5\n>HOLD:Waiting for hold release:0\n"))
+		read() // state on
+		conn.Write([]byte("SUCCESS: real-time state notification set to ON\n"))
+		read() // hold release
+		conn.Write([]byte(">STATE:1700000000,WAIT,,,,,,\n>PASSWORD:Need 'Auth' username/password SC:1,Enter code\n"))
+		read() // username
+		read() // password
+		conn.Write([]byte(">STATE:1700000001,CONNECTED,SUCCESS,10.8.0.2,203.0.113.1,1194,,\n"))
+		received <- got
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	o := &OpenVPN{}
+	err = o.Connect(ctx, &model.Profile{Name: "lab", Host: "lab.ovpn"}, &model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "pass", Push: "123456"})
+	assert.NoError(t, err)
+
+	got := <-received
+	assert.Equal(t, []string{
+		"state on",
+		"hold release",
+		`username "Auth" "alice"`,
+		`password "Auth" "SCRV1:cGFzcw==:MTIzNDU2"`,
+	}, got)
+}
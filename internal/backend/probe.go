@@ -0,0 +1,130 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// ErrProbeFailed is returned for a connect whose post-connect probes failed while
+// the profile sets fail_on_probe.
+var ErrProbeFailed = errors.New("post-connect probe failed")
+
+// ProbeObserver, when set, receives every probe result (vpnctl feeds its metrics with it).
+var ProbeObserver func(profile string, result model.ProbeResult)
+
+// RunProbes runs the profile's probes one after the other and logs each result
+// with its latency.
+func RunProbes(ctx context.Context, profile *model.Profile) []model.ProbeResult {
+	results := make([]model.ProbeResult, 0, len(profile.Probes))
+	for _, probe := range profile.Probes {
+		result := runProbe(ctx, probe)
+		if result.OK {
+			logger.Infof("Probe %v (%s %s) passed in %s", result.Name, result.Type, result.Target, result.Latency.Round(time.Millisecond))
+		} else {
+			logger.Warningf("Probe %v (%s %s) failed after %s: %s", result.Name, result.Type, result.Target, result.Latency.Round(time.Millisecond), result.Error)
+		}
+		if ProbeObserver != nil {
+			ProbeObserver(profile.Name, result)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// runProbe runs a single probe within its timeout.
+func runProbe(ctx context.Context, probe model.Probe) model.ProbeResult {
+	timeout, err := time.ParseDuration(probe.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := model.ProbeResult{Name: probe.Name, Type: probe.Type, Target: probe.Target}
+	started := time.Now()
+	switch probe.Type {
+	case model.ProbeTCP:
+		var dialer net.Dialer
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, "tcp", probe.Target); err == nil {
+			conn.Close()
+		}
+	case model.ProbeHTTP:
+		err = probeHTTP(ctx, probe)
+	case model.ProbeDNS:
+		var addrs []string
+		if addrs, err = net.DefaultResolver.LookupHost(ctx, probe.Target); err == nil && len(addrs) == 0 {
+			err = fmt.Errorf("no addresses for %s", probe.Target)
+		}
+	default:
+		err = fmt.Errorf("unsupported probe type %q", probe.Type)
+	}
+	result.Latency = time.Since(started)
+	result.OK = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// probeHTTP sends a GET and compares the status code without following redirects,
+// so a probe can expect a 302 to a login page.
+func probeHTTP(ctx context.Context, probe model.Probe) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probe.Target, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	expect := probe.ExpectStatus
+	if expect == 0 {
+		expect = http.StatusOK
+	}
+	if res.StatusCode != expect {
+		return fmt.Errorf("got HTTP %d, expected %d", res.StatusCode, expect)
+	}
+	return nil
+}
+
+// checkProbes runs the probes after a successful Connect. Failed probes only warn,
+// unless the profile sets fail_on_probe: then the tunnel is taken down again and
+// ErrProbeFailed is returned, so the attempt goes through the retry policy.
+func checkProbes(ctx context.Context, b Backend, profile *model.Profile) error {
+	if len(profile.Probes) == 0 {
+		return nil
+	}
+	var failed []string
+	for _, result := range RunProbes(ctx, profile) {
+		if !result.OK {
+			failed = append(failed, result.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	if !profile.FailOnProbe {
+		logger.Warningf("Connected to profile %v, but probes failed: %s", profile.Name, strings.Join(failed, ", "))
+		return nil
+	}
+	if err := b.Disconnect(ctx); err != nil {
+		logger.Errorf("disconnecting after failed probes: %v", err)
+	}
+	return fmt.Errorf("%w: %s", ErrProbeFailed, strings.Join(failed, ", "))
+}
@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunProbes(t *testing.T) {
+	logger.InitLogger(false, "")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closed.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer server.Close()
+
+	profile := &model.Profile{Name: "lab", Probes: []model.Probe{
+		{Name: "git", Type: model.ProbeTCP, Target: listener.Addr().String(), Timeout: "1s"},
+		{Name: "gone", Type: model.ProbeTCP, Target: closed.Addr().String(), Timeout: "1s"},
+		{Name: "jenkins", Type: model.ProbeHTTP, Target: server.URL, ExpectStatus: http.StatusFound, Timeout: "1s"},
+		{Name: "wiki", Type: model.ProbeHTTP, Target: server.URL, ExpectStatus: http.StatusOK, Timeout: "1s"},
+		{Name: "dns", Type: model.ProbeDNS, Target: "localhost", Timeout: "1s"},
+	}}
+	var observed []string
+	ProbeObserver = func(profile string, result model.ProbeResult) { observed = append(observed, profile+"/"+result.Name) }
+	defer func() { ProbeObserver = nil }()
+
+	results := RunProbes(context.Background(), profile)
+	assert.Len(t, results, 5)
+	ok := map[string]bool{}
+	for _, r := range results {
+		ok[r.Name] = r.OK
+		assert.Greater(t, r.Latency, time.Duration(0))
+	}
+	assert.Equal(t, map[string]bool{"git": true, "gone": false, "jenkins": true, "wiki": false, "dns": true}, ok)
+	assert.Contains(t, results[3].Error, "got HTTP 302, expected 200")
+	assert.Equal(t, []string{"lab/git", "lab/gone", "lab/jenkins", "lab/wiki", "lab/dns"}, observed)
+}
+
+func failingProbeProfile(failOnProbe bool) *model.Profile {
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed.Close()
+	profile := retryProfile(3)
+	profile.Retry.On[model.FailureProbe] = model.RetryActionRetry
+	profile.FailOnProbe = failOnProbe
+	profile.Probes = []model.Probe{{Name: "git", Type: model.ProbeTCP, Target: closed.Addr().String(), Timeout: "1s"}}
+	return profile
+}
+
+func TestConnectWithRetry_FailOnProbe(t *testing.T) {
+	stubSleep(t)
+	b := &scriptedBackend{}
+
+	attempts, err := ConnectWithRetry(context.Background(), b, failingProbeProfile(true), nil)
+	assert.ErrorIs(t, err, ErrProbeFailed)
+	assert.Contains(t, err.Error(), "git")
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 3, b.disconnects, "the tunnel is taken down after each failed probe")
+}
+
+func TestConnectWithRetry_ProbeFailureOnlyWarns(t *testing.T) {
+	stubSleep(t)
+	b := &scriptedBackend{}
+
+	attempts, err := ConnectWithRetry(context.Background(), b, failingProbeProfile(false), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, 0, b.disconnects)
+}
@@ -0,0 +1,155 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// AgentRecoverer is implemented by backends whose client talks to a system agent
+// that can get stuck (the recover_agent retry action).
+type AgentRecoverer interface {
+	RecoverAgent(ctx context.Context) error
+}
+
+// sleep waits between attempts; tests replace it to run without delays.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// failurePatterns maps the clients' error messages to failure classes, checked in
+// order against the lower-cased error text. They are anchored to whole messages,
+// so a port, an address or a count in the output does not match by accident.
+var failurePatterns = []struct {
+	class   string
+	pattern *regexp.Regexp
+}{
+	{model.FailureAgentLocked, regexp.MustCompile(`connect capability is unavailable`)},
+	{model.FailureAuth, regexp.MustCompile(`\blogin (failed|denied)\b|\bauthentication (failed|failures?)\b|\bauth_failed\b|\binvalid username or password\b`)},
+	{model.FailureServerBusy, regexp.MustCompile(`\bserver (is )?busy\b|\btoo many (sessions|connections|users)\b|\btry again later\b|\bmaximum number of sessions\b|\b503 service unavailable\b`)},
+	{model.FailureNetwork, regexp.MustCompile(`\bnetwork is unreachable\b|\bno route to host\b|\bunable to contact\b|\bcould not resolve\b|\bno such host\b|\bconnection refused\b`)},
+	{model.FailureTimeout, regexp.MustCompile(`\btimed out\b|\b(connection|connect|handshake|i/o|read) timeout\b|\bdeadline exceeded\b`)},
+}
+
+// Classify sorts a Connect error into one of the model.Failure* classes, first by
+// the sentinel errors backends return and then by the client's error text.
+func Classify(err error) string {
+	if err == nil {
+		return ""
+	}
+	switch {
+	case errors.Is(err, ErrAgentLocked):
+		return model.FailureAgentLocked
+	case errors.Is(err, ErrAuthFailed):
+		return model.FailureAuth
+	case errors.Is(err, ErrProbeFailed):
+		return model.FailureProbe
+	case errors.Is(err, context.DeadlineExceeded):
+		return model.FailureTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return model.FailureTimeout
+	}
+
+	text := strings.ToLower(err.Error())
+	for _, p := range failurePatterns {
+		if p.pattern.MatchString(text) {
+			return p.class
+		}
+	}
+	return model.FailureOther
+}
+
+// ConnectWithRetry calls Connect until it succeeds or the profile's retry policy
+// gives up, logging every attempt with its failure class. After a successful
+// Connect the profile's probes run (see checkProbes). It returns the number
+// of attempts made and the last error.
+func ConnectWithRetry(ctx context.Context, b Backend, profile *model.Profile, credential *model.CREDENTIAL_FOR_LOGIN) (int, error) {
+	policy := profile.Retry
+	maxAttempts := max(policy.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		logger.Infof("Connect attempt %d/%d for profile %v", attempt, maxAttempts, profile.Name)
+		if err = b.Connect(ctx, profile, credential); err == nil {
+			if err = checkProbes(ctx, b, profile); err == nil {
+				return attempt, nil
+			}
+		}
+
+		class := Classify(err)
+		action := policy.On[class]
+		if action == "" {
+			action = model.RetryActionAbort
+		}
+		logger.Warningf("Connect attempt %d/%d for profile %v failed (%s): %v", attempt, maxAttempts, profile.Name, class, err)
+
+		if action == model.RetryActionAbort {
+			logger.Errorf("Not retrying %s failures for profile %v", class, profile.Name)
+			return attempt, err
+		}
+		if attempt >= maxAttempts {
+			logger.Errorf("Giving up on profile %v after %d attempts", profile.Name, attempt)
+			return attempt, err
+		}
+
+		if action == model.RetryActionRecoverAgent {
+			if recoverer, ok := b.(AgentRecoverer); ok {
+				logger.Infof("Recovering the %v agent before the next attempt", b.Name())
+				if rerr := recoverer.RecoverAgent(ctx); rerr != nil {
+					logger.Errorf("recovering the %v agent: %v", b.Name(), rerr)
+				}
+			} else {
+				logger.Warningf("the %v backend cannot recover its agent, retrying instead", b.Name())
+			}
+		}
+
+		delay := backoff(policy, attempt)
+		logger.Infof("Retrying profile %v in %s", profile.Name, delay)
+		if serr := sleep(ctx, delay); serr != nil {
+			return attempt, fmt.Errorf("%w (retry cancelled: %v)", err, serr)
+		}
+	}
+}
+
+// backoff returns the delay after the given attempt: the base delay doubled per
+// attempt, capped at the maximum, with "equal jitter" (half fixed, half random).
+func backoff(policy model.RetryPolicy, attempt int) time.Duration {
+	base, err := time.ParseDuration(policy.BaseDelay)
+	if err != nil || base <= 0 {
+		base = 2 * time.Second
+	}
+	limit, err := time.ParseDuration(policy.MaxDelay)
+	if err != nil || limit < base {
+		limit = max(base, 30*time.Second)
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(delay, limit)
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	cases := map[string]error{
+		model.FailureAgentLocked: ErrAgentLocked,
+		model.FailureAuth:        fmt.Errorf("vpn connect: Login failed."),
+		model.FailureNetwork:     errors.New("connect: network is unreachable"),
+		model.FailureServerBusy:  errors.New("error: The server is busy, try again later"),
+		model.FailureTimeout:     fmt.Errorf("wrapped: %w", context.DeadlineExceeded),
+		model.FailureProbe:       fmt.Errorf("%w: gitlab", ErrProbeFailed),
+		model.FailureOther:       errors.New("exit status 1"),
+	}
+	for class, err := range cases {
+		assert.Equal(t, class, Classify(err), err.Error())
+	}
+	assert.Equal(t, model.FailureAuth, Classify(fmt.Errorf("openvpn: %w", ErrAuthFailed)))
+	assert.Equal(t, model.FailureAuth, Classify(errors.New("vpn connect: Too many authentication failures")))
+	assert.Equal(t, model.FailureServerBusy, Classify(errors.New("Got inappropriate HTTP CONNECT response: HTTP/1.1 503 Service Unavailable")))
+	assert.Equal(t, model.FailureTimeout, Classify(errors.New("dial tcp 192.0.2.1:443: i/o timeout")))
+	for _, text := range []string{
+		"vpn connect: error: unexpected reply from 10.0.50.3:4503",
+		"openconnect: exit status 1: keepalive timeout=30 set",
+		"error: too many redirects",
+	} {
+		assert.Equal(t, model.FailureOther, Classify(errors.New(text)), text)
+	}
+	assert.Equal(t, "", Classify(nil))
+}
+
+// scriptedBackend fails Connect with the queued errors, then succeeds.
+type scriptedBackend struct {
+	errs        []error
+	connects    int
+	disconnects int
+	recoveries  int
+}
+
+func (s *scriptedBackend) Name() string { return "scripted" }
+func (s *scriptedBackend) Connect(ctx context.Context, profile *model.Profile, credential *model.CREDENTIAL_FOR_LOGIN) error {
+	s.connects++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+func (s *scriptedBackend) Disconnect(ctx context.Context) error {
+	s.disconnects++
+	return nil
+}
+func (s *scriptedBackend) Status(ctx context.Context) (*model.VPNState, error) {
+	return &model.VPNState{State: model.StateDisconnected}, nil
+}
+func (s *scriptedBackend) Capabilities() Capabilities { return Capabilities{} }
+func (s *scriptedBackend) RecoverAgent(ctx context.Context) error {
+	s.recoveries++
+	return nil
+}
+
+func retryProfile(maxAttempts int) *model.Profile {
+	return &model.Profile{Name: "lab", Retry: model.RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   "1s",
+		MaxDelay:    "4s",
+		On: map[string]string{
+			model.FailureAgentLocked: model.RetryActionRecoverAgent,
+			model.FailureAuth:        model.RetryActionAbort,
+			model.FailureNetwork:     model.RetryActionRetry,
+		},
+	}}
+}
+
+func stubSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	logger.InitLogger(false, "")
+	var delays []time.Duration
+	orig := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	t.Cleanup(func() { sleep = orig })
+	return &delays
+}
+
+func TestConnectWithRetry_RetriesWithBackoff(t *testing.T) {
+	delays := stubSleep(t)
+	b := &scriptedBackend{errs: []error{
+		errors.New("network is unreachable"),
+		errors.New("network is unreachable"),
+		ErrAgentLocked,
+	}}
+
+	attempts, err := ConnectWithRetry(context.Background(), b, retryProfile(5), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, attempts)
+	assert.Equal(t, 1, b.recoveries)
+	assert.Len(t, *delays, 3)
+	for i, d := range *delays {
+		full := min(time.Second<<i, 4*time.Second)
+		assert.GreaterOrEqual(t, d, full/2)
+		assert.LessOrEqual(t, d, full)
+	}
+}
+
+func TestConnectWithRetry_AbortsOnAuthFailure(t *testing.T) {
+	stubSleep(t)
+	b := &scriptedBackend{errs: []error{ErrAuthFailed}}
+
+	attempts, err := ConnectWithRetry(context.Background(), b, retryProfile(5), nil)
+	assert.ErrorIs(t, err, ErrAuthFailed)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestConnectWithRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	stubSleep(t)
+	b := &scriptedBackend{errs: []error{ErrAgentLocked, ErrAgentLocked, ErrAgentLocked}}
+
+	attempts, err := ConnectWithRetry(context.Background(), b, retryProfile(2), nil)
+	assert.ErrorIs(t, err, ErrAgentLocked)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 2, b.connects)
+}
+
+func TestConnectWithRetry_UnconfiguredClassAborts(t *testing.T) {
+	stubSleep(t)
+	b := &scriptedBackend{errs: []error{errors.New("exit status 1")}}
+
+	attempts, err := ConnectWithRetry(context.Background(), b, retryProfile(3), nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
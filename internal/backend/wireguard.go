@@ -0,0 +1,198 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// WireGuard brings wg-quick configs up and down. The profile's host is the
+// config name (looked up in /etc/wireguard) or a path to a .conf file.
+// The config brought up last is remembered in the state file so Status and
+// Disconnect only ever touch the tunnel vpnctl owns.
+type WireGuard struct{}
+
+func init() {
+	Register("wireguard", func() Backend { return &WireGuard{} })
+}
+
+func (w *WireGuard) Name() string { return "wireguard" }
+
+func (w *WireGuard) Capabilities() Capabilities {
+	return Capabilities{Stats: true}
+}
+
+// Connect runs `wg-quick up <config>` and records the config in the state file.
+func (w *WireGuard) Connect(ctx context.Context, profile *model.Profile, credential *model.CREDENTIAL_FOR_LOGIN) error {
+	output, err := exec.CommandContext(ctx, config.WIREGUARD_WG_QUICK_PATH, "up", profile.Host).CombinedOutput()
+	printLines("wg-quick", output)
+	if err != nil {
+		return fmt.Errorf("wg-quick up %s: %w", profile.Host, err)
+	}
+
+	stateFile, err := w.stateFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0700); err != nil {
+		return fmt.Errorf("creating state file directory: %w", err)
+	}
+	if err := os.WriteFile(stateFile, []byte(profile.Host+"\n"), 0600); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	return nil
+}
+
+// Disconnect runs `wg-quick down` for the config brought up by Connect.
+func (w *WireGuard) Disconnect(ctx context.Context) error {
+	stateFile, err := w.stateFile()
+	if err != nil {
+		return err
+	}
+	conf, err := readState(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // nothing to disconnect
+		}
+		return err
+	}
+
+	logger.Infof("Bringing WireGuard interface %v down", wgInterface(conf))
+	output, err := exec.CommandContext(ctx, config.WIREGUARD_WG_QUICK_PATH, "down", conf).CombinedOutput()
+	printLines("wg-quick", output)
+	if err != nil {
+		return fmt.Errorf("wg-quick down %s: %w", conf, err)
+	}
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing state file: %w", err)
+	}
+	return nil
+}
+
+// Status parses `wg show <iface> dump` for the latest handshake and transfer bytes.
+func (w *WireGuard) Status(ctx context.Context) (*model.VPNState, error) {
+	stateFile, err := w.stateFile()
+	if err != nil {
+		return nil, err
+	}
+	conf, err := readState(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &model.VPNState{State: model.StateDisconnected, Raw: "no WireGuard tunnel is up"}, nil
+		}
+		return &model.VPNState{State: model.StateUnknown}, err
+	}
+
+	iface := wgInterface(conf)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, config.WIREGUARD_WG_PATH, "show", iface, "dump").Output()
+	if err != nil {
+		// the interface is gone, e.g. after a reboot
+		return &model.VPNState{State: model.StateDisconnected, Raw: fmt.Sprintf("WireGuard interface %s is down", iface)}, nil
+	}
+
+	state, err := parseWGDump(string(output))
+	if err != nil {
+		return &model.VPNState{State: model.StateUnknown, Raw: string(output)}, err
+	}
+	state.Host = iface
+	state.Raw = formatWGState(iface, state)
+	return state, nil
+}
+
+func (w *WireGuard) stateFile() (string, error) {
+	return middleware.ExpandPath(config.WIREGUARD_STATE_FILE)
+}
+
+// parseWGDump parses the tab separated output of `wg show <iface> dump`.
+// The first line describes the interface, every further line is a peer:
+// public-key, preshared-key, endpoint, allowed-ips, latest-handshake, rx, tx, keepalive.
+// Handshakes are reduced to the most recent one and transfer bytes are summed over peers.
+func parseWGDump(dump string) (*model.VPNState, error) {
+	lines := strings.Split(strings.TrimSpace(dump), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		return nil, fmt.Errorf("empty wg dump")
+	}
+
+	state := &model.VPNState{State: model.StateConnected}
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 8 {
+			return nil, fmt.Errorf("unexpected wg dump peer line: %q", line)
+		}
+		handshake, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latest-handshake %q: %w", fields[4], err)
+		}
+		rx, err := strconv.ParseInt(fields[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transfer-rx %q: %w", fields[5], err)
+		}
+		tx, err := strconv.ParseInt(fields[6], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transfer-tx %q: %w", fields[6], err)
+		}
+
+		if handshake > 0 {
+			if at := time.Unix(handshake, 0); state.LatestHandshake == nil || at.After(*state.LatestHandshake) {
+				state.LatestHandshake = &at
+			}
+		}
+		state.RxBytes += rx
+		state.TxBytes += tx
+	}
+	return state, nil
+}
+
+// formatWGState renders the parsed dump for `vpnctl status`.
+func formatWGState(iface string, state *model.VPNState) string {
+	handshake := "never"
+	if state.LatestHandshake != nil {
+		handshake = fmt.Sprintf("%s ago", time.Since(*state.LatestHandshake).Truncate(time.Second))
+	}
+	return fmt.Sprintf("interface: %s\nstate: %s\nlatest handshake: %s\ntransfer: %d B received, %d B sent",
+		iface, state.State, handshake, state.RxBytes, state.TxBytes)
+}
+
+// wgInterface derives the interface name wg-quick uses for a config name or path.
+func wgInterface(conf string) string {
+	return strings.TrimSuffix(filepath.Base(conf), ".conf")
+}
+
+// readState reads the single line stored in a backend state file.
+func readState(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("empty state file %s", path)
+	}
+	return value, nil
+}
+
+// printLines echoes command output line by line with a prefix.
+func printLines(prefix string, output []byte) {
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			fmt.Printf("[%s] %s\n", prefix, line)
+		}
+	}
+}
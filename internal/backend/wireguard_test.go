@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+const wgDump = "cHJpdmF0ZQ==\tcHVibGlj\t51820\toff\n" +
+	"cGVlcjE=\t(none)\t203.0.113.1:51820\t10.0.0.0/8\t1700000000\t1024\t2048\t25\n" +
+	"cGVlcjI=\t(none)\t(none)\t192.168.0.0/16\t0\t0\t100\toff\n"
+
+func TestParseWGDump(t *testing.T) {
+	state, err := parseWGDump(wgDump)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StateConnected, state.State)
+	assert.Equal(t, time.Unix(1700000000, 0), *state.LatestHandshake)
+	assert.Equal(t, int64(1024), state.RxBytes)
+	assert.Equal(t, int64(2148), state.TxBytes)
+
+	_, err = parseWGDump("")
+	assert.Error(t, err)
+	_, err = parseWGDump("iface\nbroken peer line")
+	assert.Error(t, err)
+}
+
+func TestWGInterface(t *testing.T) {
+	assert.Equal(t, "staging", wgInterface("staging"))
+	assert.Equal(t, "staging", wgInterface("/etc/wireguard/staging.conf"))
+}
+
+func TestWireGuard_Lifecycle(t *testing.T) {
+	logger.InitLogger(false, "")
+	dir := t.TempDir()
+	wgQuick := filepath.Join(dir, "wg-quick")
+	wg := filepath.Join(dir, "wg")
+	assert.NoError(t, os.WriteFile(wgQuick, []byte("#!/bin/sh\necho \"$*\" >> \"$FAKE_WG_LOG\"\n"), 0755))
+	assert.NoError(t, os.WriteFile(wg, []byte("#!/bin/sh\nprintf '"+
+		"priv\\tpub\\t51820\\toff\\npeer\\t(none)\\t203.0.113.1:51820\\t10.0.0.0/8\\t1700000000\\t10\\t20\\t25\\n'\n"), 0755))
+	t.Setenv("FAKE_WG_LOG", filepath.Join(dir, "calls"))
+
+	origQuick, origWg, origState := config.WIREGUARD_WG_QUICK_PATH, config.WIREGUARD_WG_PATH, config.WIREGUARD_STATE_FILE
+	config.WIREGUARD_WG_QUICK_PATH, config.WIREGUARD_WG_PATH = wgQuick, wg
+	config.WIREGUARD_STATE_FILE = filepath.Join(dir, "wireguard.iface")
+	defer func() {
+		config.WIREGUARD_WG_QUICK_PATH, config.WIREGUARD_WG_PATH, config.WIREGUARD_STATE_FILE = origQuick, origWg, origState
+	}()
+
+	w := &WireGuard{}
+	ctx := context.Background()
+
+	state, err := w.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StateDisconnected, state.State)
+
+	assert.NoError(t, w.Connect(ctx, &model.Profile{Name: "staging-wg", Host: "staging"}, &model.CREDENTIAL_FOR_LOGIN{}))
+	state, err = w.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StateConnected, state.State)
+	assert.Equal(t, int64(10), state.RxBytes)
+	assert.Contains(t, state.Raw, "interface: staging")
+
+	assert.NoError(t, w.Disconnect(ctx))
+	calls, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	assert.Equal(t, "up staging\ndown staging\n", string(calls))
+
+	state, err = w.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StateDisconnected, state.State)
+}
@@ -0,0 +1,156 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package handler
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+// DefaultAccount is the credential store account of the credential every profile
+// without its own entry uses.
+func DefaultAccount() string {
+	return config.KEYRING_SERVICE_NAME
+}
+
+// profileAccountPrefix starts the account of a profile's own credential entry, so
+// no profile name can collide with the default account, the data key or the
+// keyring probe.
+const profileAccountPrefix = "profile:"
+
+// ProfileAccount is the account of the profile's own credential entry.
+func ProfileAccount(profile *model.Profile) string {
+	if profile == nil {
+		return DefaultAccount()
+	}
+	return profileAccountPrefix + profile.Name
+}
+
+// accountProfileName returns the profile name of a profile's own account.
+func accountProfileName(account string) (string, bool) {
+	return strings.CutPrefix(account, profileAccountPrefix)
+}
+
+// AccountFor returns the account holding the profile's credential: its own entry
+// when one is stored, the default account otherwise.
+func AccountFor(profile *model.Profile) (string, error) {
+	if profile == nil {
+		return DefaultAccount(), nil
+	}
+	store, err := CredentialStore()
+	if err != nil {
+		return "", err
+	}
+	_, err = store.Get(ProfileAccount(profile))
+	if errors.Is(err, ErrNotFound) {
+		return DefaultAccount(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return ProfileAccount(profile), nil
+}
+
+// profileOf returns the configured profile owning an account, or nil for the
+// default account.
+func profileOf(account string) *model.Profile {
+	name, ok := accountProfileName(account)
+	if !ok {
+		return nil
+	}
+	profile, err := config.ResolveProfile(name)
+	if err != nil {
+		return nil
+	}
+	return profile
+}
+
+// CredentialEntry describes a stored credential for `vpnctl credential list`,
+// without its secrets.
+type CredentialEntry struct {
+	Account   string     `json:"account"`
+	Profiles  []string   `json:"profiles"` // configured profiles using the entry
+	Username  string     `json:"username"`
+	MFA       string     `json:"mfa"`
+	RotatedAt time.Time  `json:"rotated_at"`
+	Expiry    *time.Time `json:"expiry,omitempty"`
+}
+
+// ListCredentials returns the default credential and the profiles' own entries
+// that are stored.
+func ListCredentials() ([]CredentialEntry, error) {
+	var entries []CredentialEntry
+	var usingDefault []string
+	for i := range config.VPN_PROFILES {
+		profile := &config.VPN_PROFILES[i]
+		account, err := AccountFor(profile)
+		if err != nil {
+			return nil, err
+		}
+		if account == DefaultAccount() {
+			usingDefault = append(usingDefault, profile.Name)
+			continue
+		}
+		entry, err := credentialEntry(account)
+		if err != nil {
+			return nil, err
+		}
+		entry.Profiles = []string{profile.Name}
+		entries = append(entries, entry)
+	}
+
+	entry, err := credentialEntry(DefaultAccount())
+	if errors.Is(err, ErrNotFound) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry.Profiles = usingDefault
+	return append([]CredentialEntry{entry}, entries...), nil
+}
+
+// CredentialExpiries returns the recorded expiry dates of the default account and
+// the configured profiles' own accounts. It reads only the expiry table, never the
+// credential store, so nothing is decrypted, migrated or prompted for.
+func CredentialExpiries() ([]CredentialEntry, error) {
+	accounts := []string{DefaultAccount()}
+	for i := range config.VPN_PROFILES {
+		accounts = append(accounts, ProfileAccount(&config.VPN_PROFILES[i]))
+	}
+	var entries []CredentialEntry
+	for _, account := range accounts {
+		expiry, err := CredentialExpiry(account)
+		if errors.Is(err, ErrNoExpiry) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry := CredentialEntry{Account: account, Expiry: &expiry}
+		if name, ok := accountProfileName(account); ok {
+			entry.Profiles = []string{name}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func credentialEntry(account string) (CredentialEntry, error) {
+	record, err := LoadRecord(account)
+	if err != nil {
+		return CredentialEntry{}, err
+	}
+	entry := CredentialEntry{Account: account, Username: record.Username, MFA: record.MFA, RotatedAt: record.RotatedAt}
+	if expiry, err := CredentialExpiry(account); err == nil {
+		entry.Expiry = &expiry
+	}
+	return entry, nil
+}
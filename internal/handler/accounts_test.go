@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useProfiles configures the profiles for the test.
+func useProfiles(t *testing.T, profiles ...model.Profile) {
+	t.Helper()
+	orig := config.VPN_PROFILES
+	config.VPN_PROFILES = profiles
+	t.Cleanup(func() { config.VPN_PROFILES = orig })
+}
+
+func TestGetStoredCredential_ProfileAccount(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	useProfiles(t, model.Profile{Name: "intra", MFA: "none"}, model.Profile{Name: "dev", MFA: "none"})
+	intra, dev := &config.VPN_PROFILES[0], &config.VPN_PROFILES[1]
+
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "default"}))
+	require.NoError(t, StoreCredential(ProfileAccount(dev), model.CREDENTIAL_FOR_LOGIN{Username: "contractor", Password: "gateway"}))
+
+	cred, err := GetStoredCredential(intra)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", cred.Username, "falls back to the default account")
+	cred, err = GetStoredCredential(dev)
+	require.NoError(t, err)
+	assert.Equal(t, "contractor", cred.Username)
+	assert.Equal(t, "gateway", cred.Password.Reveal())
+
+	record, err := LoadRecord("profile:dev")
+	require.NoError(t, err)
+	assert.Equal(t, "dev", record.Profile)
+
+	require.NoError(t, middleware.SetExpiryToDB("profile:dev", "2020-01-01"))
+	_, err = GetStoredCredential(dev)
+	assert.ErrorIs(t, err, ErrCredentialExpired, "each account has its own expiry")
+	_, err = GetStoredCredential(intra)
+	assert.NoError(t, err)
+}
+
+func TestListCredentials(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	useProfiles(t, model.Profile{Name: "intra"}, model.Profile{Name: "dev"}, model.Profile{Name: "lab"})
+
+	entries, err := ListCredentials()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "default"}))
+	require.NoError(t, StoreCredential("profile:dev", model.CREDENTIAL_FOR_LOGIN{Username: "contractor", Password: "gateway"}))
+
+	entries, err = ListCredentials()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, DefaultAccount(), entries[0].Account)
+	assert.Equal(t, []string{"intra", "lab"}, entries[0].Profiles)
+	assert.Equal(t, "alice", entries[0].Username)
+	assert.NotNil(t, entries[0].Expiry, "storing a credential starts its expiry")
+	assert.Equal(t, "profile:dev", entries[1].Account)
+	assert.Equal(t, []string{"dev"}, entries[1].Profiles)
+	assert.Equal(t, "contractor", entries[1].Username)
+}
+
+func TestProfileAccount_NoCollisions(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	useProfiles(t, model.Profile{Name: DefaultAccount()}, model.Profile{Name: dataKeyAccount}, model.Profile{Name: keyringProbeAccount})
+
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "default"}))
+	for i := range config.VPN_PROFILES {
+		account := ProfileAccount(&config.VPN_PROFILES[i])
+		assert.NotContains(t, []string{DefaultAccount(), dataKeyAccount, keyringProbeAccount}, account)
+		require.NoError(t, StoreCredential(account, model.CREDENTIAL_FOR_LOGIN{Username: "bob", Password: "profile password"}))
+	}
+
+	record, err := LoadRecord(DefaultAccount())
+	require.NoError(t, err, "the data key and the default credential are untouched")
+	assert.Equal(t, "alice", record.Username)
+	assert.Equal(t, "default", record.Password.Reveal())
+}
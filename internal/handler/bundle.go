@@ -0,0 +1,334 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+// bundleHeader starts an exported bundle, followed by the key derivation
+// parameters and, on the next line, the Bundle as JSON sealed with Encrypt.
+const bundleHeader = "vpnctl-bundle/1"
+
+// Bundle is the vpnctl state `vpnctl export` moves to another machine.
+type Bundle struct {
+	CreatedAt time.Time         `json:"created_at"`
+	Profiles  string            `json:"profiles"` // [[profile]] tables as TOML
+	Records   map[string]string `json:"records"`  // credential records by account, as stored
+	Expiry    map[string]string `json:"expiry"`   // password expiry dates by account
+	Sessions  []model.Session   `json:"sessions"` // connection history
+}
+
+// ExportBundle collects the configured profiles, the stored credentials with
+// their expiry dates and the connection history.
+func ExportBundle() (*Bundle, error) {
+	profiles, err := config.ProfileTables()
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{CreatedAt: now().UTC(), Profiles: profiles, Records: map[string]string{}, Expiry: map[string]string{}}
+
+	entries, err := ListCredentials()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		record, err := LoadRecord(entry.Account)
+		if err != nil {
+			return nil, err
+		}
+		if b.Records[entry.Account], err = encodeRecord(record); err != nil {
+			return nil, err
+		}
+		if entry.Expiry != nil {
+			b.Expiry[entry.Account] = entry.Expiry.Format(expiryDateLayout)
+		}
+	}
+
+	if b.Sessions, err = middleware.ListSessions(time.Time{}, ""); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// SealBundle encrypts the bundle with a key derived from the passphrase.
+func SealBundle(b *Bundle, passphrase string) ([]byte, error) {
+	kdf, key, err := newPassphraseKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("encoding bundle: %w", err)
+	}
+	sealed, err := Encrypt(string(plain), string(key))
+	if err != nil {
+		return nil, fmt.Errorf("encrypting bundle: %w", err)
+	}
+	return []byte(fmt.Sprintf("%s %s\n%s\n", bundleHeader, kdf, sealed)), nil
+}
+
+// OpenBundle decrypts a bundle written by SealBundle.
+func OpenBundle(data []byte, passphrase string) (*Bundle, error) {
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	header := strings.Fields(lines[0])
+	if len(lines) != 2 || len(header) != 2 || header[0] != bundleHeader {
+		return nil, fmt.Errorf("not a vpnctl bundle")
+	}
+	key, err := passphraseKey(header[1], passphrase)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := Decrypt(strings.TrimSpace(lines[1]), string(key))
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase for the bundle")
+	}
+	var b Bundle
+	if err := json.Unmarshal([]byte(plain), &b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	return &b, nil
+}
+
+// Bundle change kinds, shown as the first column of the import diff.
+const (
+	ChangeAdd    = "+" // new on this machine
+	ChangeUpdate = "~" // replaces the local one
+	ChangeKeep   = "!" // differs, the local one is kept
+	ChangeHide   = "-" // no longer in effect after the import
+)
+
+// BundleChange is one difference between a bundle and the local state.
+type BundleChange struct {
+	Kind   string
+	Item   string // e.g. "profile dev" or "credential vpnctl"
+	Detail string
+}
+
+func (c BundleChange) String() string {
+	if c.Detail == "" {
+		return fmt.Sprintf("%s %s", c.Kind, c.Item)
+	}
+	return fmt.Sprintf("%s %s (%s)", c.Kind, c.Item, c.Detail)
+}
+
+// ImportPlan is what importing a bundle changes locally. Nothing local is
+// overwritten except a credential the bundle has rotated more recently.
+type ImportPlan struct {
+	Changes []BundleChange
+
+	profiles []map[string]any // merged [[profile]] tables, nil when unchanged
+	records  map[string]*model.CredentialRecord
+	expiry   map[string]time.Time
+	sessions []model.Session
+}
+
+// PlanImport compares the bundle with the local state. Profiles and history the
+// machine lacks are added; a profile with the same name is kept as it is. A
+// credential is added when the account has none, and replaces the local one when
+// the bundle's password was rotated later.
+func PlanImport(b *Bundle) (*ImportPlan, error) {
+	plan := &ImportPlan{records: map[string]*model.CredentialRecord{}, expiry: map[string]time.Time{}}
+	if err := plan.mergeProfiles(b.Profiles); err != nil {
+		return nil, err
+	}
+	if err := plan.mergeCredentials(b); err != nil {
+		return nil, err
+	}
+	if err := plan.mergeSessions(b.Sessions); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (plan *ImportPlan) mergeProfiles(doc string) error {
+	imported, err := config.ParseProfiles(doc)
+	if err != nil {
+		return err
+	}
+	current, err := config.ProfileTables()
+	if err != nil {
+		return err
+	}
+	local, err := config.ParseProfiles(current)
+	if err != nil {
+		return err
+	}
+	byName := map[string]map[string]any{}
+	for _, table := range local {
+		byName[fmt.Sprint(table["name"])] = table
+	}
+	// only the user file is written, so embedded and system profiles are not copied
+	user, err := config.UserProfileTables()
+	if err != nil {
+		return err
+	}
+	// profiles set in a file above the user configuration would hide the imported ones
+	shadowedBy := config.ProfilesShadowedBy()
+	merged := user
+	for _, table := range imported {
+		name := fmt.Sprint(table["name"])
+		existing, ok := byName[name]
+		switch {
+		case !ok && shadowedBy != "":
+			plan.Changes = append(plan.Changes, BundleChange{Kind: ChangeKeep, Item: "profile " + name, Detail: "not imported, the profiles are set in " + shadowedBy})
+		case !ok:
+			merged = append(merged, table)
+			plan.Changes = append(plan.Changes, BundleChange{Kind: ChangeAdd, Item: "profile " + name})
+		case !reflect.DeepEqual(existing, table):
+			plan.Changes = append(plan.Changes, BundleChange{Kind: ChangeKeep, Item: "profile " + name, Detail: "differs, keeping the local one"})
+		}
+	}
+	if len(merged) == len(user) {
+		return nil
+	}
+	plan.profiles = merged
+	if len(user) == 0 {
+		// the user file's [[profile]] array replaces the lower layers' as a whole
+		for _, table := range local {
+			plan.Changes = append(plan.Changes, BundleChange{Kind: ChangeHide, Item: "profile " + fmt.Sprint(table["name"]),
+				Detail: "from a lower configuration layer, replaced by the imported profiles"})
+		}
+	}
+	return nil
+}
+
+func (plan *ImportPlan) mergeCredentials(b *Bundle) error {
+	accounts := make([]string, 0, len(b.Records))
+	for account := range b.Records {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	for _, account := range accounts {
+		record, err := decodeRecord(b.Records[account])
+		if err != nil {
+			return fmt.Errorf("credential %s: %w", account, err)
+		}
+		var expiry time.Time
+		if date, ok := b.Expiry[account]; ok {
+			if expiry, err = time.ParseInLocation(expiryDateLayout, date, time.Local); err != nil {
+				return fmt.Errorf("credential %s: unreadable expiry date %q", account, date)
+			}
+		}
+
+		item := "credential " + account
+		var rotatedBefore time.Time
+		local, err := LoadRecord(account)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			plan.records[account] = record
+			plan.Changes = append(plan.Changes, BundleChange{Kind: ChangeAdd, Item: item, Detail: "user " + record.Username})
+		case err != nil:
+			return err
+		case sameCredential(local, record):
+			if _, err := CredentialExpiry(account); errors.Is(err, ErrNoExpiry) && !expiry.IsZero() {
+				plan.expiry[account] = expiry
+				plan.Changes = append(plan.Changes, BundleChange{Kind: ChangeAdd, Item: "expiry of " + item, Detail: expiry.Format(expiryDateLayout)})
+			}
+			continue
+		case record.RotatedAt.After(local.RotatedAt):
+			plan.records[account] = record
+			rotatedBefore = local.RotatedAt
+			plan.Changes = append(plan.Changes, BundleChange{Kind: ChangeUpdate, Item: item,
+				Detail: fmt.Sprintf("rotated %s, the local one %s", record.RotatedAt.Format(expiryDateLayout), local.RotatedAt.Format(expiryDateLayout))})
+		default:
+			plan.Changes = append(plan.Changes, BundleChange{Kind: ChangeKeep, Item: item, Detail: "the local one was rotated more recently"})
+			continue
+		}
+		if expiry.IsZero() && !record.RotatedAt.IsZero() {
+			// a local expiry belongs to the password being replaced
+			expiry = record.RotatedAt.AddDate(0, 0, passwordMaxAgeDays(profileOf(account), rotatedBefore, record.RotatedAt))
+		}
+		if !expiry.IsZero() {
+			plan.expiry[account] = expiry
+		}
+	}
+	return nil
+}
+
+// sameCredential reports whether two records hold the same credential, whenever
+// they were written.
+func sameCredential(a, b *model.CredentialRecord) bool {
+	return a.Username == b.Username && a.Password == b.Password && a.MFA == b.MFA && maps.Equal(a.Fields, b.Fields)
+}
+
+func (plan *ImportPlan) mergeSessions(sessions []model.Session) error {
+	local, err := middleware.ListSessions(time.Time{}, "")
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, s := range local {
+		known[sessionKey(s)] = true
+	}
+	for _, s := range sessions {
+		// a session still open in the bundle belongs to the other machine's tunnel
+		if s.EndedAt == nil || known[sessionKey(s)] {
+			continue
+		}
+		known[sessionKey(s)] = true
+		plan.sessions = append(plan.sessions, s)
+	}
+	if len(plan.sessions) > 0 {
+		detail := fmt.Sprintf("%d sessions", len(plan.sessions))
+		if len(plan.sessions) == 1 {
+			detail = "1 session"
+		}
+		plan.Changes = append(plan.Changes, BundleChange{Kind: ChangeAdd, Item: "connection history", Detail: detail})
+	}
+	return nil
+}
+
+func sessionKey(s model.Session) string {
+	return s.Profile + "|" + s.StartedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// Empty reports whether the bundle has nothing the local state lacks.
+func (plan *ImportPlan) Empty() bool {
+	return plan.profiles == nil && len(plan.records) == 0 && len(plan.expiry) == 0 && len(plan.sessions) == 0
+}
+
+// ApplyImport makes the planned changes.
+func ApplyImport(plan *ImportPlan) error {
+	if plan.profiles != nil {
+		if _, err := config.SetUserProfiles(plan.profiles); err != nil {
+			return fmt.Errorf("importing profiles: %w", err)
+		}
+	}
+	if len(plan.records) > 0 {
+		store, err := CredentialStore()
+		if err != nil {
+			return err
+		}
+		for account, record := range plan.records {
+			if err := saveRecord(store, account, record); err != nil {
+				return fmt.Errorf("importing credential %s: %w", account, err)
+			}
+		}
+	}
+	for account, expiry := range plan.expiry {
+		if err := setExpiry(account, expiry); err != nil {
+			return fmt.Errorf("importing credential %s: %w", account, err)
+		}
+	}
+	for _, s := range plan.sessions {
+		if _, err := middleware.StartSession(s); err != nil {
+			return fmt.Errorf("importing history: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useUserConfig loads the configuration with the given user configuration file
+// and returns its path.
+func useUserConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	origSystem, origUser := config.SystemConfigPath, config.UserConfigPath
+	config.SystemConfigPath, config.UserConfigPath = filepath.Join(dir, "system.toml"), filepath.Join(dir, "config.toml")
+	t.Cleanup(func() { config.SystemConfigPath, config.UserConfigPath = origSystem, origUser })
+	require.NoError(t, os.WriteFile(config.UserConfigPath, []byte(content), 0600))
+	_, err := config.LoadConfig("")
+	require.NoError(t, err)
+	return config.UserConfigPath
+}
+
+func TestBundle_ExportAndMerge(t *testing.T) {
+	setNow := stubNow(t, date("2026-03-01"))
+
+	// the old machine
+	useMockKeyring(t)
+	useTestDB(t)
+	useUserConfig(t, "[[profile]]\nname = \"intra\"\nhost = \"vpn.example.com\"\n\n[[profile]]\nname = \"lab\"\nhost = \"lab.example.com\"\n")
+	useProfiles(t, model.Profile{Name: "intra"}, model.Profile{Name: "lab"})
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "new password"}))
+	require.NoError(t, StoreCredential("profile:lab", model.CREDENTIAL_FOR_LOGIN{Username: "bob", Password: "lab password"}))
+	ended := date("2026-02-01").Add(time.Hour)
+	_, err := middleware.StartSession(model.Session{Profile: "intra", Backend: "cisco", StartedAt: date("2026-02-01"), EndedAt: &ended, EndReason: "user"})
+	require.NoError(t, err)
+	_, err = middleware.StartSession(model.Session{Profile: "lab", Backend: "cisco", StartedAt: date("2026-03-01")})
+	require.NoError(t, err)
+
+	exported, err := ExportBundle()
+	require.NoError(t, err)
+	assert.Len(t, exported.Records, 2)
+	assert.Equal(t, "2026-08-28", exported.Expiry[DefaultAccount()])
+	data, err := SealBundle(exported, "bundle pass")
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "new password")
+
+	_, err = OpenBundle(data, "wrong")
+	assert.ErrorContains(t, err, "wrong passphrase")
+	bundle, err := OpenBundle(data, "bundle pass")
+	require.NoError(t, err)
+
+	// the new machine has an older default password and its own intra profile
+	useMockKeyring(t)
+	useTestDB(t)
+	userConfig := useUserConfig(t, "[[profile]]\nname = \"intra\"\nhost = \"other.example.com\"\n\n[[profile]]\nname = \"dev\"\nhost = \"dev.example.com\"\n")
+	useProfiles(t, model.Profile{Name: "intra"}, model.Profile{Name: "dev"})
+	setNow(date("2026-01-01"))
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "old password"}))
+
+	plan, err := PlanImport(bundle)
+	require.NoError(t, err)
+	var changes []string
+	for _, c := range plan.Changes {
+		changes = append(changes, c.String())
+	}
+	assert.Equal(t, []string{
+		"! profile intra (differs, keeping the local one)",
+		"+ profile lab",
+		"+ credential profile:lab (user bob)",
+		"~ credential vpnctl-test (rotated 2026-03-01, the local one 2026-01-01)",
+		"+ connection history (1 session)",
+	}, changes)
+	require.NoError(t, ApplyImport(plan))
+
+	record, err := LoadRecord(DefaultAccount())
+	require.NoError(t, err)
+	assert.Equal(t, "new password", record.Password.Reveal())
+	expiry, err := CredentialExpiry(DefaultAccount())
+	require.NoError(t, err)
+	assert.Equal(t, date("2026-08-28"), expiry)
+	record, err = LoadRecord("profile:lab")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", record.Username)
+
+	sessions, err := middleware.ListSessions(time.Time{}, "")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1, "the session open on the old machine is left out")
+
+	cfg, err := config.LoadConfig("")
+	require.NoError(t, err)
+	var hosts []string
+	for _, p := range cfg.Profiles {
+		hosts = append(hosts, p.Name+"="+p.Host)
+	}
+	assert.Equal(t, []string{"intra=other.example.com", "dev=dev.example.com", "lab=lab.example.com"}, hosts, userConfig)
+
+	plan, err = PlanImport(bundle)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), "importing twice changes nothing")
+}
+
+func TestPlanImport_ReplacedCredentialWithoutExpiry(t *testing.T) {
+	setNow := stubNow(t, date("2026-03-01"))
+	useMockKeyring(t)
+	useTestDB(t)
+	useUserConfig(t, "")
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "new password"}))
+	bundle, err := ExportBundle()
+	require.NoError(t, err)
+	delete(bundle.Expiry, DefaultAccount()) // e.g. exported before the expiry was known
+
+	useMockKeyring(t)
+	useTestDB(t)
+	setNow(date("2026-01-01"))
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "old password"}))
+	old, err := CredentialExpiry(DefaultAccount())
+	require.NoError(t, err)
+
+	plan, err := PlanImport(bundle)
+	require.NoError(t, err)
+	require.NoError(t, ApplyImport(plan))
+	expiry, err := CredentialExpiry(DefaultAccount())
+	require.NoError(t, err)
+	assert.NotEqual(t, old, expiry, "the expiry of the replaced password is not kept")
+	assert.Equal(t, date("2026-03-01").AddDate(0, 0, passwordMaxAgeDays(nil, date("2026-01-01"), date("2026-03-01"))), expiry)
+}
+
+func TestPlanImport_ProfilesShadowedByConfigPath(t *testing.T) {
+	stubNow(t, date("2026-03-01"))
+	useMockKeyring(t)
+	useTestDB(t)
+	bundle := &Bundle{Profiles: "[[profile]]\nname = \"lab\"\nhost = \"lab.example.com\"\n"}
+
+	userConfig := useUserConfig(t, "")
+	explicit := filepath.Join(t.TempDir(), "vpnctl.toml")
+	require.NoError(t, os.WriteFile(explicit, []byte("[[profile]]\nname = \"intra\"\nhost = \"vpn.example.com\"\n"), 0600))
+	_, err := config.LoadConfig(explicit)
+	require.NoError(t, err)
+
+	plan, err := PlanImport(bundle)
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 1)
+	assert.Equal(t, "! profile lab (not imported, the profiles are set in "+explicit+")", plan.Changes[0].String())
+	assert.True(t, plan.Empty())
+	require.NoError(t, ApplyImport(plan))
+	data, err := os.ReadFile(userConfig)
+	require.NoError(t, err)
+	assert.Empty(t, string(data), "the user configuration is left alone")
+}
+
+func TestPlanImport_WritesOnlyTheUserProfiles(t *testing.T) {
+	stubNow(t, date("2026-03-01"))
+	useMockKeyring(t)
+	useTestDB(t)
+	bundle := &Bundle{Profiles: "[[profile]]\nname = \"lab\"\nhost = \"lab.example.com\"\n"}
+
+	userConfig := useUserConfig(t, "[[profile]]\nname = \"mine\"\nhost = \"mine.example.com\"\n")
+	plan, err := PlanImport(bundle)
+	require.NoError(t, err)
+	require.NoError(t, ApplyImport(plan))
+	written, err := config.UserProfileTables()
+	require.NoError(t, err)
+	var names []string
+	for _, table := range written {
+		names = append(names, table["name"].(string))
+	}
+	assert.Equal(t, []string{"mine", "lab"}, names, userConfig)
+
+	// without profiles of its own, the user file hides the embedded ones once written
+	useUserConfig(t, "")
+	embedded, err := config.ProfileTables()
+	require.NoError(t, err)
+	plan, err = PlanImport(bundle)
+	require.NoError(t, err)
+	var changes []string
+	for _, c := range plan.Changes {
+		changes = append(changes, c.String())
+	}
+	assert.Contains(t, changes, "+ profile lab")
+	assert.Len(t, changes, 1+strings.Count(embedded, "[[profile]]"))
+	assert.Contains(t, changes, "- profile intra (from a lower configuration layer, replaced by the imported profiles)")
+	require.NoError(t, ApplyImport(plan))
+	written, err = config.UserProfileTables()
+	require.NoError(t, err)
+	require.Len(t, written, 1, "the embedded profiles are not copied")
+	assert.Equal(t, "lab", written[0]["name"])
+}
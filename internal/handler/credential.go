@@ -11,6 +11,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -18,150 +19,237 @@ import (
 	"time"
 
 	"github.com/goo-apps/vpnctl/config"
-	"github.com/goo-apps/vpnctl/internal/middleware"
 	"github.com/goo-apps/vpnctl/internal/model"
 	"github.com/goo-apps/vpnctl/logger"
 	"github.com/zalando/go-keyring"
 	"golang.org/x/term"
 )
 
-// Save credential securely
-func StoreCredential(cred model.CREDENTIAL_FOR_LOGIN) error {
-	// encrypt the creds
-	encryptedPassword, err := Encrypt(cred.Password, config.KEYRING_ENCRYPTION_KEY)
+// Save credential securely under the account, DefaultAccount() or a profile's own.
+// A changed password gets a new expiry counted from now, as does a credential
+// without a recorded one. The stored MFA mode is kept unless cred asks for push.
+func StoreCredential(account string, cred model.CREDENTIAL_FOR_LOGIN) error {
+	record := &model.CredentialRecord{
+		Username: cred.Username,
+		Password: cred.Password,
+		MFA:      "none",
+	}
+	previous, err := LoadRecord(account)
 	if err != nil {
-		return fmt.Errorf("failed to encrypt password: %w", err)
+		previous = nil
+	} else if previous.MFA != "" {
+		record.MFA = previous.MFA
+	}
+	if cred.Push == "push" {
+		record.MFA = "push"
+	}
+	if name, ok := accountProfileName(account); ok {
+		record.Profile = name
+	}
+	if err := SaveRecord(account, record); err != nil {
+		return err
 	}
-	cred.Password = encryptedPassword
-	// Construct single string with newline separators
-	encoded := strings.Join([]string{
-		cred.Username,
-		cred.Password,
-		cred.Push,
-		cred.YFlag,
-	}, "\n")
 
-	// Store in keychain under the profile(vpnctl) name
-	if err := keyring.Set(config.KEYRING_SERVICE_NAME, config.KEYRING_SERVICE_NAME, encoded); err != nil {
-		return fmt.Errorf("failed to store credentials: %w", err)
+	var rotatedBefore time.Time
+	if previous != nil {
+		rotatedBefore = previous.RotatedAt
+	}
+	_, err = CredentialExpiry(account)
+	if previous == nil || previous.Password != record.Password || errors.Is(err, ErrNoExpiry) {
+		return setExpiry(account, record.RotatedAt.AddDate(0, 0, passwordMaxAgeDays(profileOf(account), rotatedBefore, record.RotatedAt)))
 	}
 	return nil
 }
 
-// Get credential securely
-func GetCredential() (creds model.CREDENTIAL_FOR_LOGIN, err error) {
-	entry, err := keyring.Get(config.KEYRING_SERVICE_NAME, config.KEYRING_SERVICE_NAME)
+// Get credential securely from the account
+func GetCredential(account string) (creds model.CREDENTIAL_FOR_LOGIN, err error) {
+	record, err := LoadRecord(account)
 	if err != nil {
 		return model.CREDENTIAL_FOR_LOGIN{}, err
 	}
-
-	parts := strings.SplitN(entry, "\n", 4)
-	if len(parts) != 4 {
-		return model.CREDENTIAL_FOR_LOGIN{}, fmt.Errorf("invalid credential format")
+	creds.Username = record.Username
+	creds.Password = record.Password
+	if record.MFA == "push" {
+		creds.Push = "push"
 	}
+	creds.YFlag = "y"
+	return creds, nil
+}
 
-	// decrypt the password
-	// decryptedPassword, err := Decrypt(parts[1], config.KEYRING_ENCRYPTION_KEY)
-	// if err != nil {
-	// 	return model.CREDENTIAL_FOR_LOGIN{}, fmt.Errorf("failed to decrypt password: %w", err)
-	// }
-	creds.Username = parts[0]
-	creds.Password = parts[1]
-	creds.Push = parts[2]
-	creds.YFlag = parts[3]
+// ErrNoCredential is returned when no usable credential is stored.
+var ErrNoCredential = errors.New("no usable credential stored")
 
-	return creds, nil
+// ErrCredentialExpired is returned when the stored password has expired. It is an
+// ErrNoCredential.
+var ErrCredentialExpired = fmt.Errorf("%w: the password expired", ErrNoCredential)
+
+// GetStoredCredential returns the stored credential for the given profile, from
+// its own entry or the default one, without prompting. It fails when nothing is stored or the stored credential has expired,
+// which is what unattended callers such as the daemon need. Credentials from the
+// env store have no recorded expiry.
+func GetStoredCredential(profile *model.Profile) (*model.CREDENTIAL_FOR_LOGIN, error) {
+	store, err := CredentialStore()
+	if err != nil {
+		return nil, err
+	}
+	account, err := AccountFor(profile)
+	if err != nil {
+		return nil, err
+	}
+	if store.Name() != StoreEnv { // the environment manages its own rotation
+		expiry, err := CredentialExpiry(account)
+		if err != nil {
+			return nil, fmt.Errorf("%w, run `vpnctl credential update` first", ErrNoCredential)
+		}
+		if !now().Before(expiry) {
+			return nil, fmt.Errorf("%w on %s, run `vpnctl credential rotate`", ErrCredentialExpired, expiry.Format(expiryDateLayout))
+		}
+	}
+	stored, err := LoadRecord(account)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w in the %s store, run `vpnctl credential update`", ErrNoCredential, store.Name())
+	}
+	if err != nil {
+		return nil, err
+	}
+	credential := &model.CREDENTIAL_FOR_LOGIN{
+		Username: stored.Username,
+		Password: stored.Password,
+		Push:     secondPasswordFor(profile),
+		YFlag:    "y",
+	}
+	if err := withSecondFactor(profile, stored, credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
 }
 
-func GetOrPromptCredential() (*model.CREDENTIAL_FOR_LOGIN, error) {
+// GetOrPromptCredential returns the stored credential for the given profile,
+// prompting the user when nothing is stored yet or the stored one has expired.
+// The entered password is stored with its change date, which starts the expiry.
+// The second password is derived from the profile's MFA mode; totp profiles need
+// a seed stored with `vpnctl credential totp set`.
+func GetOrPromptCredential(profile *model.Profile) (*model.CREDENTIAL_FOR_LOGIN, error) {
 	var credential model.CREDENTIAL_FOR_LOGIN
 
-	// Check expiry in db first
-	expiryStr, err := middleware.GetExpiryFromDB(config.KEYRING_SERVICE_NAME)
-	if err == nil && expiryStr != "" {
-		expiry, _ := time.Parse("2006-01-02", expiryStr)
-		if time.Now().Before(expiry) {
-			// Not expired, check keyring
-			entry, err := keyring.Get(config.KEYRING_SERVICE_NAME, config.KEYRING_SERVICE_NAME)
-			if err == nil {
-				parts := strings.SplitN(entry, "\n", 4)
-				if len(parts) == 4 {
-					decryptedPassword, err := Decrypt(parts[1], config.KEYRING_ENCRYPTION_KEY)
-					if err != nil {
-						return &credential, fmt.Errorf("failed to decrypt password: %w", err)
-					}
-					credential.Username = parts[0]
-					credential.Password = decryptedPassword
-					credential.Push = "push"
-					credential.YFlag = "y"
-					return &credential, nil
-				}
-			}
-		}
+	stored, err := GetStoredCredential(profile)
+	if err == nil {
+		return stored, nil
+	}
+	if !errors.Is(err, ErrNoCredential) {
+		return &credential, err
 	}
 
-	// Prompt the user (first time)
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println()
 	fmt.Println("┌────────────────────────────────────────────────────┐")
-	fmt.Println("│           🔐 CREDENTIAL SETUP REQUIRED             │")
-	fmt.Println("├────────────────────────────────────────────────────┤")
-	fmt.Println("│ ❌ No credential is found in the local store.      │")
-	fmt.Println("│ 📌 This is required only during *first setup*.     │")
-	fmt.Println("│ 🔑 Please enter your credential to proceed.        │")
+	if errors.Is(err, ErrCredentialExpired) {
+		fmt.Println("│           🔁 CREDENTIAL ROTATION REQUIRED          │")
+		fmt.Println("├────────────────────────────────────────────────────┤")
+		fmt.Println("│ ⌛ Your stored VPN password has expired.           │")
+		fmt.Println("│ 🔑 Please enter your new password to proceed.      │")
+	} else {
+		// Prompt the user (first time)
+		fmt.Println("│           🔐 CREDENTIAL SETUP REQUIRED             │")
+		fmt.Println("├────────────────────────────────────────────────────┤")
+		fmt.Println("│ ❌ No credential is found in the local store.      │")
+		fmt.Println("│ 📌 This is required only during *first setup*.     │")
+		fmt.Println("│ 🔑 Please enter your credential to proceed.        │")
+	}
 	fmt.Println("└────────────────────────────────────────────────────┘")
 	fmt.Println()
 
-	fmt.Printf("Enter username for '%s': ", config.KEYRING_SERVICE_NAME)
-	username, _ := reader.ReadString('\n')
-	username = strings.TrimSpace(username)
+	account, err := AccountFor(profile)
+	if err != nil {
+		return &credential, err
+	}
+	username := ""
+	if record, rerr := LoadRecord(account); rerr == nil {
+		username = record.Username
+	}
+	if username == "" {
+		fmt.Printf("Enter username for '%s': ", account)
+		username, _ = reader.ReadString('\n')
+		username = strings.TrimSpace(username)
+	}
 
 	fmt.Print("Enter password: ")
 	bytePassword, _ := term.ReadPassword(int(os.Stdin.Fd()))
 	password := string(bytePassword)
 	fmt.Println()
 
-	push := "push"
-	y_flag := "y"
-
-	encryptedPassword, err := Encrypt(password, config.KEYRING_ENCRYPTION_KEY)
+	changedOn, err := PromptChangeDate(reader)
 	if err != nil {
-		return &model.CREDENTIAL_FOR_LOGIN{}, fmt.Errorf("failed to encrypt password: %w", err)
+		return &credential, err
 	}
-	password = encryptedPassword
 
-	// Store securely
-	go func() {
-		cred := username + "\n" + password + "\n" + push + "\n" + y_flag
-		if err := keyring.Set(config.KEYRING_SERVICE_NAME, config.KEYRING_SERVICE_NAME, cred); err != nil {
-			logger.Errorf("failed to store credentials: %v", err)
+	push := secondPasswordFor(profile)
+	y_flag := "y"
+
+	// Store securely; the store encrypts the record, the caller gets the
+	// plaintext password to log in with
+	credential = model.CREDENTIAL_FOR_LOGIN{Username: username, Password: model.Secret(password), Push: push, YFlag: y_flag}
+	if expiry, err := RotateCredential(profile, credential, changedOn); err != nil {
+		logger.Errorf("%v", err)
+	} else {
+		logger.Infof("Stored the credential, the password expires on %s", expiry.Format(expiryDateLayout))
+	}
+	if profile != nil && profile.MFA == "totp" {
+		record, err := LoadRecord(account)
+		if err != nil {
+			return &credential, err
 		}
-	}()
-	go func() {
-		// Store expiry in db
-		// Set expiry in DB (180 days from now)
-		expiry := time.Now().Add(180 * 24 * time.Hour).Format("2006-01-02")
-		if err := middleware.SetExpiryToDB(config.KEYRING_SERVICE_NAME, expiry); err != nil {
-			logger.Errorf("failed to set expiry: %v", err)
+		if err := withSecondFactor(profile, record, &credential); err != nil {
+			return &credential, err
 		}
-	}()
-
-	credential.Username = username
-	credential.Password = password
-	credential.Push = push
-	credential.YFlag = y_flag
+	}
 
 	return &credential, nil
 }
 
-// remove credential from key ring
-func RemoveCredential() error {
-	err := keyring.Delete(config.KEYRING_SERVICE_NAME, config.KEYRING_SERVICE_NAME)
+// secondPasswordFor returns the second password sent for the profile's MFA mode.
+func secondPasswordFor(profile *model.Profile) string {
+	if profile != nil && profile.MFA == "push" {
+		return "push"
+	}
+	return ""
+}
+
+// remove the account's credential from the credential store
+func RemoveCredential(account string) error {
+	store, err := CredentialStore()
 	if err != nil {
 		return err
 	}
-	logger.Warningf("Your credential has been removed from keyring!!")
+	if err := store.Delete(account); err != nil {
+		return err
+	}
+	logger.Warningf("Your credential %s has been removed from the %s store!!", account, store.Name())
+	return nil
+}
+
+// keyringProbeAccount is the throwaway account CheckKeyring writes to.
+const keyringProbeAccount = "vpnctl-doctor"
+
+// CheckKeyring verifies the OS keyring with a Set/Get/Delete round trip on a
+// throwaway entry, leaving the stored credential alone.
+func CheckKeyring() error {
+	value := fmt.Sprintf("probe-%d", time.Now().UnixNano())
+	if err := keyring.Set(config.KEYRING_SERVICE_NAME, keyringProbeAccount, value); err != nil {
+		return fmt.Errorf("keyring set: %w", err)
+	}
+	got, err := keyring.Get(config.KEYRING_SERVICE_NAME, keyringProbeAccount)
+	if err != nil {
+		keyring.Delete(config.KEYRING_SERVICE_NAME, keyringProbeAccount)
+		return fmt.Errorf("keyring get: %w", err)
+	}
+	if err := keyring.Delete(config.KEYRING_SERVICE_NAME, keyringProbeAccount); err != nil {
+		return fmt.Errorf("keyring delete: %w", err)
+	}
+	if got != value {
+		return fmt.Errorf("keyring returned a different value than was stored")
+	}
 	return nil
 }
 
@@ -198,6 +286,9 @@ func Decrypt(encryptedText, key string) (string, error) {
 		return "", err
 	}
 	nonceSize := aesGCM.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("cipher text too short")
+	}
 	nonce, cipherText := data[:nonceSize], data[nonceSize:]
 	plainText, err := aesGCM.Open(nil, nonce, cipherText, nil)
 	if err != nil {
@@ -0,0 +1,245 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+// dataKeyAccount is the keyring item holding the per-user data key that encrypts
+// the other keyring items.
+const dataKeyAccount = "vpnctl-data-key"
+
+// EnvPassphrase supplies the data key passphrase to unattended runs such as the daemon.
+const EnvPassphrase = "VPNCTL_PASSPHRASE"
+
+// Stored data key formats: "raw$<key>" or, wrapped with a passphrase,
+// "argon2id$<time>$<memory KiB>$<threads>$<salt>$<key sealed with Encrypt>".
+const (
+	dataKeyRaw      = "raw"
+	dataKeyArgon2id = "argon2id"
+)
+
+// argon2idParams are the key derivation costs for new wrappings; existing ones
+// keep the parameters they were written with.
+var argon2idParams = struct {
+	time    uint32
+	memory  uint32 // KiB
+	threads uint8
+}{time: 3, memory: 64 * 1024, threads: 4}
+
+var (
+	dataKeyMu sync.Mutex
+	dataKey   []byte // unwrapped key, cached for the process
+
+	// readPassphrase asks for the data key passphrase; replaced in tests.
+	readPassphrase = promptPassphrase
+)
+
+// DataKey returns the per-user AES-256 data key, creating a random one in the OS
+// keyring on first use. A passphrase-wrapped key is unwrapped with VPNCTL_PASSPHRASE
+// or a prompt.
+func DataKey() ([]byte, error) {
+	dataKeyMu.Lock()
+	defer dataKeyMu.Unlock()
+	if dataKey != nil {
+		return dataKey, nil
+	}
+
+	stored, err := keyring.Get(config.KEYRING_SERVICE_NAME, dataKeyAccount)
+	if errors.Is(err, keyring.ErrNotFound) {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("generating data key: %w", err)
+		}
+		if err := storeDataKey(key, ""); err != nil {
+			return nil, err
+		}
+		logger.Infof("Generated a new data key in the keyring")
+		dataKey = key
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading data key from keyring: %w", err)
+	}
+
+	key, err := unwrapDataKey(stored)
+	if err != nil {
+		return nil, err
+	}
+	dataKey = key
+	return key, nil
+}
+
+// SetDataKeyPassphrase wraps the data key with a new passphrase, or stores it
+// unwrapped when the passphrase is empty. Stored credentials stay readable.
+func SetDataKeyPassphrase(passphrase string) error {
+	key, err := DataKey()
+	if err != nil {
+		return err
+	}
+	dataKeyMu.Lock()
+	defer dataKeyMu.Unlock()
+	logger.RegisterSecret(passphrase)
+	return storeDataKey(key, passphrase)
+}
+
+// DataKeyWrapped reports whether the stored data key is wrapped with a passphrase.
+func DataKeyWrapped() (bool, error) {
+	stored, err := keyring.Get(config.KEYRING_SERVICE_NAME, dataKeyAccount)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(stored, dataKeyArgon2id+"$"), nil
+}
+
+func storeDataKey(key []byte, passphrase string) error {
+	encoded := base64.StdEncoding.EncodeToString(key)
+	stored := dataKeyRaw + "$" + encoded
+	if passphrase != "" {
+		kdf, kek, err := newPassphraseKey(passphrase)
+		if err != nil {
+			return err
+		}
+		sealed, err := Encrypt(encoded, string(kek))
+		if err != nil {
+			return fmt.Errorf("wrapping data key: %w", err)
+		}
+		stored = kdf + "$" + sealed
+	}
+	if err := keyring.Set(config.KEYRING_SERVICE_NAME, dataKeyAccount, stored); err != nil {
+		return fmt.Errorf("storing data key in keyring: %w", err)
+	}
+	return nil
+}
+
+func unwrapDataKey(stored string) ([]byte, error) {
+	parts := strings.Split(stored, "$")
+	var encoded string
+	switch {
+	case parts[0] == dataKeyRaw && len(parts) == 2:
+		encoded = parts[1]
+	case parts[0] == dataKeyArgon2id && len(parts) == 6:
+		kek, err := derivePassphraseKey(strings.Join(parts[:5], "$"))
+		if err != nil {
+			return nil, err
+		}
+		if encoded, err = Decrypt(parts[5], string(kek)); err != nil {
+			return nil, fmt.Errorf("wrong passphrase for the data key")
+		}
+	default:
+		return nil, fmt.Errorf("invalid data key in keyring")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid data key in keyring")
+	}
+	return key, nil
+}
+
+// newPassphraseKey derives a key from the passphrase with a new salt. kdf records
+// the parameters as argon2id$time$memory$threads$salt for derivePassphraseKey.
+func newPassphraseKey(passphrase string) (kdf string, key []byte, err error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", nil, fmt.Errorf("generating salt: %w", err)
+	}
+	p := argon2idParams
+	kdf = fmt.Sprintf("%s$%d$%d$%d$%s", dataKeyArgon2id, p.time, p.memory, p.threads, base64.StdEncoding.EncodeToString(salt))
+	return kdf, argon2.IDKey([]byte(passphrase), salt, p.time, p.memory, p.threads, 32), nil
+}
+
+// derivePassphraseKey asks for the passphrase and derives the key with the
+// recorded parameters.
+func derivePassphraseKey(kdf string) ([]byte, error) {
+	if _, err := parseKDF(kdf); err != nil {
+		return nil, err
+	}
+	passphrase, err := readPassphrase("Enter vpnctl passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	return passphraseKey(kdf, passphrase)
+}
+
+// passphraseKey derives the key of a passphrase with the recorded parameters.
+func passphraseKey(kdf, passphrase string) ([]byte, error) {
+	derive, err := parseKDF(kdf)
+	if err != nil {
+		return nil, err
+	}
+	logger.RegisterSecret(passphrase)
+	return derive(passphrase), nil
+}
+
+// parseKDF parses argon2id$time$memory$threads$salt.
+func parseKDF(kdf string) (func(passphrase string) []byte, error) {
+	parts := strings.Split(kdf, "$")
+	if len(parts) != 5 || parts[0] != dataKeyArgon2id {
+		return nil, fmt.Errorf("unsupported key derivation %q", kdf)
+	}
+	time, terr := strconv.ParseUint(parts[1], 10, 32)
+	memory, merr := strconv.ParseUint(parts[2], 10, 32)
+	threads, perr := strconv.ParseUint(parts[3], 10, 8)
+	salt, serr := base64.StdEncoding.DecodeString(parts[4])
+	if err := errors.Join(terr, merr, perr, serr); err != nil {
+		return nil, fmt.Errorf("invalid key derivation parameters: %w", err)
+	}
+	return func(passphrase string) []byte {
+		return argon2.IDKey([]byte(passphrase), salt, uint32(time), uint32(memory), uint8(threads), 32)
+	}, nil
+}
+
+// promptPassphrase reads the passphrase from VPNCTL_PASSPHRASE or the terminal.
+func promptPassphrase(prompt string) (string, error) {
+	if passphrase := os.Getenv(EnvPassphrase); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("a passphrase is needed and there is no terminal to ask for it, set %s", EnvPassphrase)
+	}
+	fmt.Print(prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("reading passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// decryptPassword decrypts the password field of a keyring entry written before
+// entries were sealed: with the data key, or the legacy KEYRING_ENCRYPTION_KEY for
+// entries older than the data key.
+func decryptPassword(encrypted string) (string, error) {
+	key, err := DataKey()
+	if err != nil {
+		return "", err
+	}
+	password, err := Decrypt(encrypted, string(key))
+	if err == nil {
+		return password, nil
+	}
+	if config.KEYRING_ENCRYPTION_KEY != "" {
+		if password, lerr := Decrypt(encrypted, config.KEYRING_ENCRYPTION_KEY); lerr == nil {
+			return password, nil
+		}
+	}
+	return "", err
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+// legacyKey is the shared key older versions encrypted passwords with.
+const legacyKey = "+7u13LXxwNcInI2UbPLRYA=="
+
+// useMockKeyring starts every test with an empty keyring, no cached data key and
+// cheap key derivation.
+func useMockKeyring(t *testing.T) {
+	t.Helper()
+	logger.InitLogger(false, "")
+	keyring.MockInit()
+	origService, origLegacy, origStore, origParams := config.KEYRING_SERVICE_NAME, config.KEYRING_ENCRYPTION_KEY, config.CREDENTIAL_STORE, argon2idParams
+	config.KEYRING_SERVICE_NAME, config.KEYRING_ENCRYPTION_KEY, config.CREDENTIAL_STORE = "vpnctl-test", legacyKey, StoreKeyring
+	argon2idParams.time, argon2idParams.memory = 1, 1024
+	dataKey, activeStore = nil, nil
+	t.Cleanup(func() {
+		config.KEYRING_SERVICE_NAME, config.KEYRING_ENCRYPTION_KEY, config.CREDENTIAL_STORE, argon2idParams = origService, origLegacy, origStore, origParams
+		dataKey, activeStore, readPassphrase = nil, nil, promptPassphrase
+	})
+}
+
+func TestDataKey_CreatedOnce(t *testing.T) {
+	useMockKeyring(t)
+
+	key, err := DataKey()
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	dataKey = nil // a new process reads it back from the keyring
+	again, err := DataKey()
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	stored, err := keyring.Get(config.KEYRING_SERVICE_NAME, dataKeyAccount)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored, "raw$"))
+}
+
+func TestDataKey_Passphrase(t *testing.T) {
+	useMockKeyring(t)
+	key, err := DataKey()
+	require.NoError(t, err)
+	require.NoError(t, SetDataKeyPassphrase("correct horse"))
+
+	wrapped, err := DataKeyWrapped()
+	require.NoError(t, err)
+	assert.True(t, wrapped)
+	stored, _ := keyring.Get(config.KEYRING_SERVICE_NAME, dataKeyAccount)
+	assert.NotContains(t, stored, base64.StdEncoding.EncodeToString(key))
+
+	dataKey = nil
+	readPassphrase = func(string) (string, error) { return "wrong", nil }
+	_, err = DataKey()
+	assert.ErrorContains(t, err, "wrong passphrase")
+
+	readPassphrase = func(string) (string, error) { return "correct horse", nil }
+	unwrapped, err := DataKey()
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrapped)
+
+	require.NoError(t, SetDataKeyPassphrase(""))
+	wrapped, _ = DataKeyWrapped()
+	assert.False(t, wrapped)
+}
+
+func TestPromptPassphrase_Env(t *testing.T) {
+	t.Setenv(EnvPassphrase, "from-env")
+	passphrase, err := promptPassphrase("")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", passphrase)
+}
+
+func TestGetStoredCredential_MigratesLegacyEntry(t *testing.T) {
+	useMockKeyring(t)
+	origDB := config.SQLITE_DB_PATH
+	config.SQLITE_DB_PATH = filepath.Join(t.TempDir(), "vpnctl.db")
+	t.Cleanup(func() { config.SQLITE_DB_PATH = origDB })
+	_, err := middleware.InitDB()
+	require.NoError(t, err)
+	require.NoError(t, middleware.SetExpiryToDB(config.KEYRING_SERVICE_NAME, time.Now().Add(24*time.Hour).Format("2006-01-02")))
+
+	legacy, err := Encrypt("s3cret", legacyKey)
+	require.NoError(t, err)
+	require.NoError(t, keyring.Set(config.KEYRING_SERVICE_NAME, config.KEYRING_SERVICE_NAME, "alice\n"+legacy+"\n\ny"))
+
+	cred, err := GetStoredCredential(&model.Profile{Name: "intra", MFA: "none"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", cred.Username)
+	assert.Equal(t, "s3cret", cred.Password.Reveal())
+
+	raw, err := keyring.Get(config.KEYRING_SERVICE_NAME, config.KEYRING_SERVICE_NAME)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, sealedPrefix), "the entry is re-encrypted with the data key")
+	assert.NotContains(t, raw, legacy)
+
+	cred, err = GetStoredCredential(&model.Profile{Name: "intra", MFA: "none"})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", cred.Password.Reveal())
+}
@@ -0,0 +1,173 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package handler
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+// expiryDateLayout is how expiry and password change dates are stored and entered.
+const expiryDateLayout = "2006-01-02"
+
+// ExpiryReminderDays are the days before the password expires at which status and
+// connect start reminding, each more urgent than the one before.
+var ExpiryReminderDays = []int{14, 7, 1}
+
+// defaultPasswordMaxAgeDays is the password lifetime used when none is configured
+// and none could be learned yet.
+const defaultPasswordMaxAgeDays = 180
+
+// minLearnedMaxAgeDays is the shortest interval between two password changes that
+// is taken as the password lifetime; shorter ones are early rotations.
+const minLearnedMaxAgeDays = 30
+
+// ErrNoExpiry is returned by CredentialExpiry when no expiry date is recorded.
+var ErrNoExpiry = errors.New("no credential expiry recorded")
+
+// CredentialExpiry returns the date the password stored under the account expires.
+func CredentialExpiry(account string) (time.Time, error) {
+	expiryStr, err := middleware.GetExpiryFromDB(account)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && expiryStr == "") {
+		return time.Time{}, ErrNoExpiry
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	expiry, err := time.ParseInLocation(expiryDateLayout, expiryStr, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unreadable expiry date %q", expiryStr)
+	}
+	return expiry, nil
+}
+
+// ExpiryReminder returns a reminder when the password the profile (nil for the
+// default credential) uses expires within the first of ExpiryReminderDays or has
+// expired, and "" otherwise.
+func ExpiryReminder(profile *model.Profile, at time.Time) string {
+	if config.CREDENTIAL_STORE == StoreEnv {
+		return ""
+	}
+	account, err := AccountFor(profile)
+	if err != nil {
+		return ""
+	}
+	expiry, err := CredentialExpiry(account)
+	if err != nil {
+		return ""
+	}
+	rotate := "vpnctl credential rotate"
+	if account != DefaultAccount() {
+		rotate += " --profile " + profile.Name
+	}
+	return expiryReminder(expiry, at, rotate)
+}
+
+func expiryReminder(expiry, at time.Time, rotate string) string {
+	days := daysBetween(at, expiry)
+	date := expiry.Format(expiryDateLayout)
+	switch {
+	case days <= 0:
+		return fmt.Sprintf("Your VPN password expired on %s, change it and run `%s`", date, rotate)
+	case days <= ExpiryReminderDays[2]:
+		return fmt.Sprintf("Your VPN password expires tomorrow (%s), change it now and run `%s`", date, rotate)
+	case days <= ExpiryReminderDays[1]:
+		return fmt.Sprintf("Your VPN password expires in %d days (%s), change it soon and run `%s`", days, date, rotate)
+	case days <= ExpiryReminderDays[0]:
+		return fmt.Sprintf("Your VPN password expires in %d days (%s), run `%s` after changing it", days, date, rotate)
+	default:
+		return ""
+	}
+}
+
+// daysBetween counts the calendar days from a to b.
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	from := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	to := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// RotateCredential stores a new password, changed on the given date, in the entry
+// the profile uses (nil for the default) and resets the expiry from the password
+// lifetime. It returns the new expiry date.
+func RotateCredential(profile *model.Profile, cred model.CREDENTIAL_FOR_LOGIN, changedOn time.Time) (time.Time, error) {
+	account, err := AccountFor(profile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	record := &model.CredentialRecord{MFA: "none"}
+	if stored, err := LoadRecord(account); err == nil {
+		record = stored // keeps the MFA mode and further fields
+	}
+	previous := record.RotatedAt
+	record.Username, record.Password, record.RotatedAt = cred.Username, cred.Password, changedOn
+	if cred.Push == "push" {
+		record.MFA = "push"
+	}
+	if err := SaveRecord(account, record); err != nil {
+		return time.Time{}, err
+	}
+
+	expiry := changedOn.AddDate(0, 0, passwordMaxAgeDays(profile, previous, changedOn))
+	if err := setExpiry(account, expiry); err != nil {
+		return time.Time{}, err
+	}
+	return expiry, nil
+}
+
+func setExpiry(account string, expiry time.Time) error {
+	if err := middleware.SetExpiryToDB(account, expiry.Format(expiryDateLayout)); err != nil {
+		return fmt.Errorf("failed to set expiry: %w", err)
+	}
+	return nil
+}
+
+// passwordMaxAgeDays is the lifetime of a password changed on changedOn: the
+// profile's password_max_age_days, then [credential] password_max_age_days, then
+// the interval since the previous change, then defaultPasswordMaxAgeDays.
+func passwordMaxAgeDays(profile *model.Profile, previous, changedOn time.Time) int {
+	if profile != nil && profile.PasswordMaxAgeDays > 0 {
+		return profile.PasswordMaxAgeDays
+	}
+	if config.CREDENTIAL_MAX_AGE_DAYS > 0 {
+		return config.CREDENTIAL_MAX_AGE_DAYS
+	}
+	if !previous.IsZero() {
+		if days := daysBetween(previous, changedOn); days >= minLearnedMaxAgeDays {
+			return days
+		}
+	}
+	return defaultPasswordMaxAgeDays
+}
+
+// PromptChangeDate asks when the password was changed; an empty answer is today.
+func PromptChangeDate(reader *bufio.Reader) (time.Time, error) {
+	fmt.Print("Password changed on (YYYY-MM-DD, empty for today): ")
+	answer, _ := reader.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	today := now()
+	if answer == "" {
+		return time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local), nil
+	}
+	changedOn, err := time.ParseInLocation(expiryDateLayout, answer, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", answer)
+	}
+	if changedOn.After(today) {
+		return time.Time{}, fmt.Errorf("the change date %s is in the future", answer)
+	}
+	return changedOn, nil
+}
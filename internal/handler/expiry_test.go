@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestDB points the SQLite database at a temporary file.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	origDB := config.SQLITE_DB_PATH
+	config.SQLITE_DB_PATH = filepath.Join(t.TempDir(), "vpnctl.db")
+	t.Cleanup(func() { config.SQLITE_DB_PATH = origDB })
+	_, err := middleware.InitDB()
+	require.NoError(t, err)
+}
+
+func date(s string) time.Time {
+	d, _ := time.ParseInLocation(expiryDateLayout, s, time.Local)
+	return d
+}
+
+func TestExpiryReminder(t *testing.T) {
+	expiry := date("2026-03-20")
+	for _, tt := range []struct {
+		at   string
+		want string
+	}{
+		{"2026-03-01", ""},
+		{"2026-03-06", "expires in 14 days"},
+		{"2026-03-13", "expires in 7 days, change it soon"},
+		{"2026-03-19", "expires tomorrow"},
+		{"2026-03-20", "expired on 2026-03-20"},
+	} {
+		got := expiryReminder(expiry, date(tt.at).Add(15*time.Hour), "vpnctl credential rotate")
+		if tt.want == "" {
+			assert.Empty(t, got, tt.at)
+		} else {
+			assert.Contains(t, strings.ReplaceAll(got, " (2026-03-20)", ""), tt.want, tt.at)
+		}
+	}
+}
+
+func TestExpiryReminder_ProfileAccount(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	stubNow(t, date("2026-01-01"))
+	useProfiles(t, model.Profile{Name: "dev", MFA: "none"})
+	orig := config.CREDENTIAL_MAX_AGE_DAYS
+	config.CREDENTIAL_MAX_AGE_DAYS = 0
+	t.Cleanup(func() { config.CREDENTIAL_MAX_AGE_DAYS = orig })
+	dev := &config.VPN_PROFILES[0]
+	require.NoError(t, StoreCredential(ProfileAccount(dev), model.CREDENTIAL_FOR_LOGIN{Username: "bob", Password: "dev password"}))
+
+	assert.Equal(t, "Your VPN password expires tomorrow (2026-06-30), change it now and run `vpnctl credential rotate --profile dev`",
+		ExpiryReminder(dev, date("2026-06-29")))
+}
+
+func TestPasswordMaxAgeDays(t *testing.T) {
+	orig := config.CREDENTIAL_MAX_AGE_DAYS
+	t.Cleanup(func() { config.CREDENTIAL_MAX_AGE_DAYS = orig })
+	changed := date("2026-06-01")
+
+	config.CREDENTIAL_MAX_AGE_DAYS = 0
+	assert.Equal(t, defaultPasswordMaxAgeDays, passwordMaxAgeDays(nil, time.Time{}, changed))
+	assert.Equal(t, 90, passwordMaxAgeDays(nil, date("2026-03-03"), changed), "learned from the previous change")
+	assert.Equal(t, defaultPasswordMaxAgeDays, passwordMaxAgeDays(nil, date("2026-05-25"), changed), "an early rotation is not learned")
+
+	config.CREDENTIAL_MAX_AGE_DAYS = 60
+	assert.Equal(t, 60, passwordMaxAgeDays(nil, date("2026-03-03"), changed))
+	assert.Equal(t, 30, passwordMaxAgeDays(&model.Profile{PasswordMaxAgeDays: 30}, date("2026-03-03"), changed))
+}
+
+func TestRotateCredential(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	orig := config.CREDENTIAL_MAX_AGE_DAYS
+	config.CREDENTIAL_MAX_AGE_DAYS = 0
+	t.Cleanup(func() { config.CREDENTIAL_MAX_AGE_DAYS = orig })
+
+	expiry, err := RotateCredential(nil, model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "one", Push: "push"}, date("2026-01-01"))
+	require.NoError(t, err)
+	assert.Equal(t, date("2026-06-30"), expiry)
+
+	expiry, err = RotateCredential(nil, model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "two"}, date("2026-04-01"))
+	require.NoError(t, err)
+	assert.Equal(t, date("2026-06-30"), expiry, "90 days learned from the two changes")
+	stored, err := CredentialExpiry(DefaultAccount())
+	require.NoError(t, err)
+	assert.Equal(t, expiry, stored)
+
+	record, err := LoadRecord(config.KEYRING_SERVICE_NAME)
+	require.NoError(t, err)
+	assert.Equal(t, "two", record.Password.Reveal())
+	assert.Equal(t, "push", record.MFA, "the MFA mode is kept")
+	assert.True(t, record.RotatedAt.Equal(date("2026-04-01")))
+}
+
+func TestStoreCredential_ChangedPasswordGetsNewExpiry(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	setNow := stubNow(t, date("2026-01-01"))
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "one"}))
+	_, err := SetTOTPSeed(DefaultAccount(), "JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	require.NoError(t, middleware.SetExpiryToDB(config.KEYRING_SERVICE_NAME, "2026-03-01"))
+
+	// same password: the expiry stays
+	setNow(date("2026-02-01"))
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "one"}))
+	expiry, err := CredentialExpiry(DefaultAccount())
+	require.NoError(t, err)
+	assert.Equal(t, date("2026-03-01"), expiry)
+
+	// after it expired, `credential update` stores the new password with a new expiry
+	setNow(date("2026-03-02"))
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "two"}))
+	expiry, err = CredentialExpiry(DefaultAccount())
+	require.NoError(t, err)
+	assert.True(t, expiry.After(date("2026-03-02")), expiry)
+	_, err = GetStoredCredential(nil)
+	assert.NoError(t, err)
+
+	record, err := LoadRecord(DefaultAccount())
+	require.NoError(t, err)
+	assert.Equal(t, "totp", record.MFA, "the MFA mode is kept")
+}
+
+func TestGetStoredCredential_Expired(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "s3cret"}))
+	require.NoError(t, middleware.SetExpiryToDB(config.KEYRING_SERVICE_NAME, "2020-01-01"))
+
+	_, err := GetStoredCredential(nil)
+	assert.ErrorIs(t, err, ErrCredentialExpired)
+	assert.ErrorIs(t, err, ErrNoCredential)
+}
+
+func TestPromptChangeDate(t *testing.T) {
+	stubNow(t, date("2026-05-10").Add(9*time.Hour))
+
+	changed, err := PromptChangeDate(bufio.NewReader(strings.NewReader("\n")))
+	require.NoError(t, err)
+	assert.Equal(t, date("2026-05-10"), changed)
+
+	changed, err = PromptChangeDate(bufio.NewReader(strings.NewReader("2026-05-01\n")))
+	require.NoError(t, err)
+	assert.Equal(t, date("2026-05-01"), changed)
+
+	_, err = PromptChangeDate(bufio.NewReader(strings.NewReader("2026-06-01\n")))
+	assert.ErrorContains(t, err, "in the future")
+	_, err = PromptChangeDate(bufio.NewReader(strings.NewReader("yesterday\n")))
+	assert.ErrorContains(t, err, "YYYY-MM-DD")
+}
@@ -0,0 +1,160 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// LoadRecord reads the credential record of an account from the credential
+// store, migrating a legacy newline-joined entry to a JSON record on the way.
+func LoadRecord(account string) (*model.CredentialRecord, error) {
+	store, err := CredentialStore()
+	if err != nil {
+		return nil, err
+	}
+	value, err := store.Get(account)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(value, "{") {
+		return decodeRecord(value)
+	}
+
+	record, err := legacyRecord(account, value)
+	if err != nil {
+		return nil, err
+	}
+	if store.Name() == StoreEnv {
+		return record, nil
+	}
+	if err := saveRecord(store, account, record); err != nil {
+		logger.Warningf("failed to migrate credential %s to a versioned record: %v", account, err)
+	} else {
+		logger.Infof("Migrated credential %s to a versioned record", account)
+	}
+	return record, nil
+}
+
+// SaveRecord writes the credential record of an account. The created and rotated
+// timestamps are kept, or set when the record is new or the password changed, and
+// the fields are kept unless the record brings its own.
+func SaveRecord(account string, record *model.CredentialRecord) error {
+	store, err := CredentialStore()
+	if err != nil {
+		return err
+	}
+	if previous, err := LoadRecord(account); err == nil {
+		if record.CreatedAt.IsZero() {
+			record.CreatedAt = previous.CreatedAt
+		}
+		if record.RotatedAt.IsZero() && previous.Password == record.Password {
+			record.RotatedAt = previous.RotatedAt
+		}
+		if record.Fields == nil {
+			record.Fields = previous.Fields
+		}
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now().UTC()
+	}
+	if record.RotatedAt.IsZero() {
+		record.RotatedAt = now().UTC()
+	}
+	return saveRecord(store, account, record)
+}
+
+func saveRecord(store Store, account string, record *model.CredentialRecord) error {
+	record.Version = model.CredentialRecordVersion
+	registerSecrets(record)
+	data, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+	if err := store.Set(account, data); err != nil {
+		return fmt.Errorf("failed to store credentials in %s store: %w", store.Name(), err)
+	}
+	return nil
+}
+
+// storedRecord is the form a record takes in the credential store. Its secrets
+// shadow the masked model.Secret fields so they are written in plain text.
+type storedRecord struct {
+	model.CredentialRecord
+	Password string            `json:"password"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// encodeRecord marshals a record with its secrets for the credential store.
+func encodeRecord(record *model.CredentialRecord) (string, error) {
+	stored := storedRecord{CredentialRecord: *record, Password: record.Password.Reveal()}
+	if record.Fields != nil {
+		stored.Fields = make(map[string]string, len(record.Fields))
+		for name, value := range record.Fields {
+			stored.Fields[name] = value.Reveal()
+		}
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return "", fmt.Errorf("encoding credential record: %w", err)
+	}
+	return string(data), nil
+}
+
+func decodeRecord(value string) (*model.CredentialRecord, error) {
+	var stored storedRecord
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		return nil, fmt.Errorf("invalid credential record: %w", err)
+	}
+	record := stored.CredentialRecord
+	if record.Version > model.CredentialRecordVersion {
+		return nil, fmt.Errorf("credential record version %d is newer than this vpnctl supports (%d), upgrade vpnctl",
+			record.Version, model.CredentialRecordVersion)
+	}
+	record.Password = model.Secret(stored.Password)
+	if stored.Fields != nil {
+		record.Fields = make(map[string]model.Secret, len(stored.Fields))
+		for name, value := range stored.Fields {
+			record.Fields[name] = model.Secret(value)
+		}
+	}
+	registerSecrets(&record)
+	return &record, nil
+}
+
+// registerSecrets has the logger scrub the record's secrets from its output.
+func registerSecrets(record *model.CredentialRecord) {
+	logger.RegisterSecret(record.Password.Reveal())
+	for _, value := range record.Fields {
+		logger.RegisterSecret(value.Reveal())
+	}
+}
+
+// legacyRecord converts a "username\npassword\npush\nyflag" entry. Its dates are
+// unknown and left zero. The third field was always "push", so the MFA mode is
+// taken from the profile owning the account, and is none for the default account.
+func legacyRecord(account, value string) (*model.CredentialRecord, error) {
+	parts := strings.SplitN(value, "\n", 4)
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid credential format")
+	}
+	mfa := "none"
+	if profile := profileOf(account); profile != nil && profile.MFA != "" {
+		mfa = profile.MFA
+	}
+	record := &model.CredentialRecord{Username: parts[0], Password: model.Secret(parts[1]), MFA: mfa}
+	registerSecrets(record)
+	return record, nil
+}
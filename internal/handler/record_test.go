@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubNow makes now return t0 and then whatever the returned setter says.
+func stubNow(t *testing.T, t0 time.Time) func(time.Time) {
+	t.Helper()
+	current := t0
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+	return func(t time.Time) { current = t }
+}
+
+func TestLoadRecord_MigratesLegacyEntry(t *testing.T) {
+	useMockKeyring(t)
+	store, err := CredentialStore()
+	require.NoError(t, err)
+	require.NoError(t, store.Set(config.KEYRING_SERVICE_NAME, "alice\ns3cret\npush\ny"))
+
+	record, err := LoadRecord(config.KEYRING_SERVICE_NAME)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", record.Username)
+	assert.Equal(t, "s3cret", record.Password.Reveal())
+	assert.Equal(t, "none", record.MFA, "the legacy push field is not trusted")
+
+	value, err := store.Get(config.KEYRING_SERVICE_NAME)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(value, `{"version":1,`), value)
+}
+
+func TestLoadRecord_MigratedEntryTakesTheProfilesMFA(t *testing.T) {
+	useMockKeyring(t)
+	useProfiles(t, model.Profile{Name: "intra", MFA: "none"}, model.Profile{Name: "dev", MFA: "push"})
+	store, err := CredentialStore()
+	require.NoError(t, err)
+	require.NoError(t, store.Set("profile:intra", "alice\ns3cret\npush\ny"))
+	require.NoError(t, store.Set("profile:dev", "alice\ns3cret\npush\ny"))
+
+	record, err := LoadRecord("profile:intra")
+	require.NoError(t, err)
+	assert.Equal(t, "none", record.MFA)
+	record, err = LoadRecord("profile:intra")
+	require.NoError(t, err)
+	assert.Equal(t, "none", record.MFA, "the migrated record keeps the mode")
+
+	record, err = LoadRecord("profile:dev")
+	require.NoError(t, err)
+	assert.Equal(t, "push", record.MFA)
+}
+
+func TestSaveRecord_Timestamps(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	t0 := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	setNow := stubNow(t, t0)
+
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "one"}))
+	setNow(t0.Add(24 * time.Hour))
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "one", Push: "push"}))
+	record, err := LoadRecord(config.KEYRING_SERVICE_NAME)
+	require.NoError(t, err)
+	assert.Equal(t, t0, record.CreatedAt)
+	assert.Equal(t, t0, record.RotatedAt, "the password did not change")
+	assert.Equal(t, "push", record.MFA)
+
+	setNow(t0.Add(48 * time.Hour))
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "two"}))
+	record, _ = LoadRecord(config.KEYRING_SERVICE_NAME)
+	assert.Equal(t, t0, record.CreatedAt)
+	assert.Equal(t, t0.Add(48*time.Hour), record.RotatedAt)
+	assert.Equal(t, model.CredentialRecordVersion, record.Version)
+}
+
+func TestGetCredential_ReturnsPlaintext(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "s3cret", Push: "push"}))
+
+	cred, err := GetCredential(DefaultAccount())
+	require.NoError(t, err)
+	assert.Equal(t, model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "s3cret", Push: "push", YFlag: "y"}, cred)
+}
+
+func TestDecodeRecord_NewerVersion(t *testing.T) {
+	_, err := decodeRecord(`{"version":99,"username":"alice"}`)
+	assert.ErrorContains(t, err, "newer than this vpnctl supports")
+}
+
+func TestSaveRecord_StoresSecretsInPlaintext(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	record := &model.CredentialRecord{Username: "alice", Password: "s3cret", MFA: "totp",
+		Fields: map[string]model.Secret{totpSeedField: "JBSWY3DPEHPK3PXP"}}
+	require.NoError(t, SaveRecord(DefaultAccount(), record))
+
+	masked, err := json.Marshal(record)
+	require.NoError(t, err)
+	assert.NotContains(t, string(masked), "s3cret")
+	assert.NotContains(t, string(masked), "JBSWY3DPEHPK3PXP")
+
+	loaded, err := LoadRecord(DefaultAccount())
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", loaded.Password.Reveal())
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", loaded.Fields[totpSeedField].Reveal())
+}
@@ -0,0 +1,365 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/zalando/go-keyring"
+)
+
+// Credential store backends, selected by [credential] store.
+const (
+	StoreAuto    = "auto"
+	StoreKeyring = "keyring"
+	StoreFile    = "file"
+	StoreEnv     = "env"
+)
+
+// Environment variables read by the env store.
+const (
+	EnvUsername = "VPNCTL_USERNAME"
+	EnvPassword = "VPNCTL_PASSWORD"
+)
+
+// ErrNotFound is returned by a Store that has nothing under the account.
+var ErrNotFound = errors.New("not found in credential store")
+
+// ErrReadOnly is returned when writing to the env store.
+var ErrReadOnly = errors.New("the env credential store is read-only, change the environment variables instead")
+
+// Store keeps secret values by account. Values are plain text to the caller;
+// each backend protects them at rest.
+type Store interface {
+	Name() string
+	Get(account string) (string, error)
+	Set(account, value string) error
+	Delete(account string) error
+}
+
+var (
+	storeMu     sync.Mutex
+	activeStore Store // selected on first use, see CredentialStore
+)
+
+// CredentialStore returns the configured credential store. With "auto" it is the
+// OS keyring, or the encrypted file when no keyring (e.g. no D-Bus Secret Service)
+// is available.
+func CredentialStore() (Store, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if activeStore != nil {
+		return activeStore, nil
+	}
+	store, err := newStore(config.CREDENTIAL_STORE)
+	if err != nil {
+		return nil, err
+	}
+	activeStore = store
+	return store, nil
+}
+
+func newStore(name string) (Store, error) {
+	switch name {
+	case StoreKeyring:
+		return keyringStore{service: config.KEYRING_SERVICE_NAME}, nil
+	case StoreFile:
+		return newFileStore(config.CREDENTIAL_FILE)
+	case StoreEnv:
+		return envStore{}, nil
+	case StoreAuto, "":
+		if err := keyringAvailable(); err != nil {
+			logger.Infof("OS keyring unavailable (%v), using the encrypted credential file", err)
+			return newFileStore(config.CREDENTIAL_FILE)
+		}
+		return keyringStore{service: config.KEYRING_SERVICE_NAME}, nil
+	default:
+		return nil, fmt.Errorf("unknown [credential] store %q, use auto, keyring, file or env", name)
+	}
+}
+
+// keyringAvailable reads an item to find out whether a keyring answers at all.
+func keyringAvailable() error {
+	_, err := keyring.Get(config.KEYRING_SERVICE_NAME, dataKeyAccount)
+	if err == nil || errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// sealedPrefix marks keyring values encrypted as a whole with the data key.
+const sealedPrefix = "sealed$"
+
+// keyringStore keeps values in the OS keyring, encrypted with the data key.
+type keyringStore struct {
+	service string
+}
+
+func (s keyringStore) Name() string { return StoreKeyring }
+
+func (s keyringStore) Get(account string) (string, error) {
+	value, err := keyring.Get(s.service, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading keyring: %w", err)
+	}
+	if sealed, ok := strings.CutPrefix(value, sealedPrefix); ok {
+		key, err := DataKey()
+		if err != nil {
+			return "", err
+		}
+		plain, err := Decrypt(sealed, string(key))
+		if err != nil {
+			return "", fmt.Errorf("decrypting keyring item %s: %w", account, err)
+		}
+		return plain, nil
+	}
+	return s.migrate(account, value)
+}
+
+// migrate converts a credential entry written before values were sealed, whose
+// password field alone is encrypted with the data key or the legacy shared key,
+// to a plain legacy entry; LoadRecord then turns it into a record.
+func (s keyringStore) migrate(account, value string) (string, error) {
+	parts := strings.SplitN(value, "\n", 4)
+	if len(parts) != 4 {
+		return "", fmt.Errorf("invalid credential format")
+	}
+	password, err := decryptPassword(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt password: %w", err)
+	}
+	parts[1] = password
+	plain := strings.Join(parts, "\n")
+	if err := s.Set(account, plain); err != nil {
+		logger.Warningf("failed to migrate keyring item %s: %v", account, err)
+	} else {
+		logger.Infof("Migrated keyring item %s to the per-user data key", account)
+	}
+	return plain, nil
+}
+
+func (s keyringStore) Set(account, value string) error {
+	key, err := DataKey()
+	if err != nil {
+		return err
+	}
+	sealed, err := Encrypt(value, string(key))
+	if err != nil {
+		return fmt.Errorf("encrypting keyring item: %w", err)
+	}
+	if err := keyring.Set(s.service, account, sealedPrefix+sealed); err != nil {
+		return fmt.Errorf("failed to store in keyring: %w", err)
+	}
+	return nil
+}
+
+func (s keyringStore) Delete(account string) error {
+	err := keyring.Delete(s.service, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// fileHeader starts the encrypted credential file, followed by the key derivation
+// parameters and, on the next line, the accounts as JSON sealed with Encrypt.
+const fileHeader = "vpnctl-credentials/1"
+
+// fileStore keeps values in one file encrypted with a key derived from a
+// passphrase (VPNCTL_PASSPHRASE or a prompt), for machines without a keyring.
+type fileStore struct {
+	path string
+	kdf  string // argon2id$time$memory$threads$salt of the file
+	key  []byte // derived on first use
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	expanded, err := middleware.ExpandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileStore{path: expanded}, nil
+}
+
+func (s *fileStore) Name() string { return StoreFile }
+
+func (s *fileStore) Get(account string) (string, error) {
+	values, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *fileStore) Set(account, value string) error {
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+	values[account] = value
+	return s.save(values)
+}
+
+func (s *fileStore) Delete(account string) error {
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := values[account]; !ok {
+		return ErrNotFound
+	}
+	delete(values, account)
+	return s.save(values)
+}
+
+// load reads and decrypts the file; a missing file is an empty store.
+func (s *fileStore) load() (map[string]string, error) {
+	values := map[string]string{}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading credential file: %w", err)
+	}
+
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	header := strings.Fields(lines[0])
+	if len(lines) != 2 || len(header) != 2 || header[0] != fileHeader {
+		return nil, fmt.Errorf("%s is not a vpnctl credential file", s.path)
+	}
+	if s.key == nil || s.kdf != header[1] {
+		if s.key, err = derivePassphraseKey(header[1]); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.path, err)
+		}
+		s.kdf = header[1]
+	}
+	plain, err := Decrypt(strings.TrimSpace(lines[1]), string(s.key))
+	if err != nil {
+		s.key = nil
+		return nil, fmt.Errorf("wrong passphrase for %s", s.path)
+	}
+	if err := json.Unmarshal([]byte(plain), &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	return values, nil
+}
+
+// save encrypts the values and replaces the file atomically.
+func (s *fileStore) save(values map[string]string) error {
+	if s.key == nil {
+		if err := s.newKey(); err != nil {
+			return err
+		}
+	}
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	sealed, err := Encrypt(string(plain), string(s.key))
+	if err != nil {
+		return fmt.Errorf("encrypting credential file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(fmt.Sprintf("%s %s\n%s\n", fileHeader, s.kdf, sealed)), 0600); err != nil {
+		return fmt.Errorf("writing credential file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// newKey asks for the passphrase of a new credential file.
+func (s *fileStore) newKey() error {
+	passphrase, err := readPassphrase(fmt.Sprintf("Choose a passphrase for %s: ", s.path))
+	if err != nil {
+		return err
+	}
+	return s.rekey(passphrase)
+}
+
+// rekey switches the file to a new passphrase; the next save uses it.
+func (s *fileStore) rekey(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("the credential file needs a passphrase, set %s", EnvPassphrase)
+	}
+	kdf, key, err := newPassphraseKey(passphrase)
+	if err != nil {
+		return err
+	}
+	s.kdf, s.key = kdf, key
+	return nil
+}
+
+// ChangePassphrase protects the credential store with a new passphrase: it wraps
+// the keyring data key (an empty passphrase unwraps it) or re-encrypts the file.
+func ChangePassphrase(passphrase string) error {
+	store, err := CredentialStore()
+	if err != nil {
+		return err
+	}
+	switch s := store.(type) {
+	case keyringStore:
+		return SetDataKeyPassphrase(passphrase)
+	case *fileStore:
+		values, err := s.load()
+		if err != nil {
+			return err
+		}
+		if err := s.rekey(passphrase); err != nil {
+			return err
+		}
+		return s.save(values)
+	default:
+		return fmt.Errorf("the %s credential store has no passphrase", store.Name())
+	}
+}
+
+// envStore reads the credential from VPNCTL_USERNAME and VPNCTL_PASSWORD, and a
+// TOTP seed from VPNCTL_TOTP_SEED, for CI and containers that inject secrets into
+// the environment. It is read-only.
+type envStore struct{}
+
+func (envStore) Name() string { return StoreEnv }
+
+func (envStore) Get(account string) (string, error) {
+	username, password := os.Getenv(EnvUsername), os.Getenv(EnvPassword)
+	if account != config.KEYRING_SERVICE_NAME || username == "" || password == "" {
+		return "", ErrNotFound
+	}
+	record := model.CredentialRecord{
+		Version:  model.CredentialRecordVersion,
+		Username: username,
+		Password: model.Secret(password),
+		MFA:      "none",
+	}
+	if seed := os.Getenv(EnvTOTPSeed); seed != "" {
+		record.MFA, record.Fields = "totp", map[string]model.Secret{totpSeedField: model.Secret(seed)}
+	}
+	return encodeRecord(&record)
+}
+
+func (envStore) Set(string, string) error { return ErrReadOnly }
+
+func (envStore) Delete(string) error { return ErrReadOnly }
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func TestFileStore(t *testing.T) {
+	useMockKeyring(t)
+	readPassphrase = func(string) (string, error) { return "hunter2", nil }
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+
+	store, err := newFileStore(path)
+	require.NoError(t, err)
+	_, err = store.Get("vpnctl")
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, store.Set("vpnctl", "alice\ns3cret\n\ny"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cret")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	reopened, _ := newFileStore(path)
+	value, err := reopened.Get("vpnctl")
+	require.NoError(t, err)
+	assert.Equal(t, "alice\ns3cret\n\ny", value)
+
+	readPassphrase = func(string) (string, error) { return "wrong", nil }
+	reopened, _ = newFileStore(path)
+	_, err = reopened.Get("vpnctl")
+	assert.ErrorContains(t, err, "wrong passphrase")
+
+	require.NoError(t, store.Delete("vpnctl"))
+	assert.ErrorIs(t, store.Delete("vpnctl"), ErrNotFound)
+}
+
+func TestFileStore_ChangePassphrase(t *testing.T) {
+	useMockKeyring(t)
+	config.CREDENTIAL_STORE, config.CREDENTIAL_FILE = StoreFile, filepath.Join(t.TempDir(), "credentials.enc")
+	readPassphrase = func(string) (string, error) { return "old", nil }
+	store, err := CredentialStore()
+	require.NoError(t, err)
+	require.NoError(t, store.Set("vpnctl", "entry"))
+
+	require.NoError(t, ChangePassphrase("new"))
+	readPassphrase = func(string) (string, error) { return "new", nil }
+	reopened, _ := newFileStore(config.CREDENTIAL_FILE)
+	value, err := reopened.Get("vpnctl")
+	require.NoError(t, err)
+	assert.Equal(t, "entry", value)
+}
+
+func TestEnvStore(t *testing.T) {
+	useMockKeyring(t)
+	store := envStore{}
+	_, err := store.Get(config.KEYRING_SERVICE_NAME)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	t.Setenv(EnvUsername, "ci-bot")
+	t.Setenv(EnvPassword, "token")
+	value, err := store.Get(config.KEYRING_SERVICE_NAME)
+	require.NoError(t, err)
+	cred, err := decodeRecord(value)
+	require.NoError(t, err)
+	assert.Equal(t, "ci-bot", cred.Username)
+	assert.Equal(t, "token", cred.Password.Reveal())
+	assert.ErrorIs(t, store.Set(config.KEYRING_SERVICE_NAME, "x"), ErrReadOnly)
+}
+
+func TestNewStore_AutoFallsBackToFile(t *testing.T) {
+	useMockKeyring(t)
+	store, err := newStore(StoreAuto)
+	require.NoError(t, err)
+	assert.Equal(t, StoreKeyring, store.Name())
+
+	keyring.MockInitWithError(errors.New("org.freedesktop.DBus.Error.ServiceUnknown"))
+	t.Cleanup(keyring.MockInit)
+	store, err = newStore(StoreAuto)
+	require.NoError(t, err)
+	assert.Equal(t, StoreFile, store.Name())
+
+	_, err = newStore("vault")
+	assert.Error(t, err)
+}
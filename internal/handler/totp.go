@@ -0,0 +1,182 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+// ErrNoTOTPSeed is returned when the credential has no TOTP seed.
+var ErrNoTOTPSeed = errors.New("no TOTP seed stored")
+
+// totpSeedField is the credential record field holding the TOTP seed.
+const totpSeedField = "totp_seed"
+
+// EnvTOTPSeed supplies the TOTP seed to the env store.
+const EnvTOTPSeed = "VPNCTL_TOTP_SEED"
+
+// totpKey is a parsed TOTP seed with its RFC 6238 parameters.
+type totpKey struct {
+	secret []byte
+	hash   func() hash.Hash
+	digits int
+	period time.Duration
+}
+
+// parseTOTPSeed accepts a base32 secret, as authenticator apps show it, or an
+// otpauth://totp/ URI from an enrolment QR code. Bare secrets use the common
+// defaults: SHA1, 6 digits and a 30 second period.
+func parseTOTPSeed(seed string) (*totpKey, error) {
+	key := &totpKey{hash: sha1.New, digits: 6, period: 30 * time.Second}
+	secret := seed
+	if strings.HasPrefix(seed, "otpauth://") {
+		u, err := url.Parse(seed)
+		if err != nil || u.Host != "totp" {
+			return nil, fmt.Errorf("not an otpauth://totp/ URI")
+		}
+		q := u.Query()
+		secret = q.Get("secret")
+		switch strings.ToUpper(q.Get("algorithm")) {
+		case "", "SHA1":
+		case "SHA256":
+			key.hash = sha256.New
+		case "SHA512":
+			key.hash = sha512.New
+		default:
+			return nil, fmt.Errorf("unsupported TOTP algorithm %q", q.Get("algorithm"))
+		}
+		if v := q.Get("digits"); v != "" {
+			digits, err := strconv.Atoi(v)
+			if err != nil || digits < 6 || digits > 8 {
+				return nil, fmt.Errorf("unsupported TOTP digits %q", v)
+			}
+			key.digits = digits
+		}
+		if v := q.Get("period"); v != "" {
+			period, err := strconv.Atoi(v)
+			if err != nil || period <= 0 {
+				return nil, fmt.Errorf("invalid TOTP period %q", v)
+			}
+			key.period = time.Duration(period) * time.Second
+		}
+	}
+
+	secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(secret))
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(decoded) == 0 {
+		return nil, fmt.Errorf("the TOTP seed is not a base32 secret")
+	}
+	key.secret = decoded
+	return key, nil
+}
+
+// code computes the RFC 6238 code for the time step containing at.
+func (k *totpKey) code(at time.Time) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(at.Unix()/int64(k.period/time.Second)))
+	mac := hmac.New(k.hash, k.secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < k.digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.digits, value%mod)
+}
+
+// remaining is how long the code for at stays valid.
+func (k *totpKey) remaining(at time.Time) time.Duration {
+	step := int64(k.period / time.Second)
+	return time.Duration(step-at.Unix()%step) * time.Second
+}
+
+// SetTOTPSeed stores the TOTP seed with the account's credential and switches the
+// record to the totp MFA mode. It returns the current code so the enrolment can be
+// checked against the authenticator app.
+func SetTOTPSeed(account, seed string) (string, error) {
+	seed = strings.TrimSpace(seed)
+	key, err := parseTOTPSeed(seed)
+	if err != nil {
+		return "", err
+	}
+	record, err := LoadRecord(account)
+	if errors.Is(err, ErrNotFound) {
+		return "", fmt.Errorf("%w, store it with `vpnctl credential update` first", ErrNoCredential)
+	}
+	if err != nil {
+		return "", err
+	}
+	if record.Fields == nil {
+		record.Fields = map[string]model.Secret{}
+	}
+	record.Fields[totpSeedField] = model.Secret(seed)
+	record.MFA = "totp"
+	if err := SaveRecord(account, record); err != nil {
+		return "", err
+	}
+	return key.code(now()), nil
+}
+
+// TOTPCode returns the current code from the seed stored with the account's
+// credential and how long it stays valid.
+func TOTPCode(account string) (string, time.Duration, error) {
+	record, err := LoadRecord(account)
+	if err != nil {
+		return "", 0, err
+	}
+	key, err := totpKeyOf(record)
+	if err != nil {
+		return "", 0, err
+	}
+	at := now()
+	return key.code(at), key.remaining(at), nil
+}
+
+func totpKeyOf(record *model.CredentialRecord) (*totpKey, error) {
+	seed := record.Fields[totpSeedField]
+	if seed == "" {
+		return nil, ErrNoTOTPSeed
+	}
+	key, err := parseTOTPSeed(seed.Reveal())
+	if err != nil {
+		return nil, fmt.Errorf("stored TOTP seed: %w", err)
+	}
+	return key, nil
+}
+
+// withSecondFactor makes a totp profile's credential generate the code from the
+// record's seed each time the second password is asked for.
+func withSecondFactor(profile *model.Profile, record *model.CredentialRecord, cred *model.CREDENTIAL_FOR_LOGIN) error {
+	if profile == nil || profile.MFA != "totp" {
+		return nil
+	}
+	key, err := totpKeyOf(record)
+	if errors.Is(err, ErrNoTOTPSeed) {
+		return fmt.Errorf("profile %s uses totp: %w, run `vpnctl credential totp set --profile %s`", profile.Name, err, profile.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("profile %s uses totp: %w", profile.Name, err)
+	}
+	cred.SecondFactor = func() string { return key.code(now()) }
+	return nil
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTOTPCode_RFC6238 checks the test vectors of RFC 6238 appendix B.
+func TestTOTPCode_RFC6238(t *testing.T) {
+	secret := func(s string) string { return base32.StdEncoding.EncodeToString([]byte(s)) }
+	sha1Seed := secret("12345678901234567890")
+	sha256Seed := "otpauth://totp/vpnctl?digits=8&algorithm=SHA256&secret=" + secret("12345678901234567890123456789012")
+	sha512Seed := "otpauth://totp/vpnctl?digits=8&algorithm=SHA512&secret=" +
+		secret("1234567890123456789012345678901234567890123456789012345678901234")
+
+	for _, tt := range []struct {
+		seed string
+		at   int64
+		want string
+	}{
+		{"otpauth://totp/vpnctl?digits=8&secret=" + sha1Seed, 59, "94287082"},
+		{"otpauth://totp/vpnctl?digits=8&secret=" + sha1Seed, 1111111109, "07081804"},
+		{"otpauth://totp/vpnctl?digits=8&secret=" + sha1Seed, 20000000000, "65353130"},
+		{sha256Seed, 59, "46119246"},
+		{sha256Seed, 1234567890, "91819424"},
+		{sha512Seed, 59, "90693936"},
+		{sha512Seed, 2000000000, "38618901"},
+		{sha1Seed, 59, "287082"},
+	} {
+		key, err := parseTOTPSeed(tt.seed)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, key.code(time.Unix(tt.at, 0)), "%s at %d", tt.seed, tt.at)
+	}
+}
+
+func TestParseTOTPSeed(t *testing.T) {
+	key, err := parseTOTPSeed("jbsw y3dp-ehpk 3pxp")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("Hello!\xde\xad\xbe\xef"), key.secret, "spaces, dashes and lower case are accepted")
+	assert.Equal(t, 20*time.Second, key.remaining(time.Unix(70, 0)))
+
+	for _, seed := range []string{"", "not base32!", "otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP", "otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&digits=4"} {
+		_, err := parseTOTPSeed(seed)
+		assert.Error(t, err, seed)
+	}
+}
+
+func TestGetStoredCredential_TOTP(t *testing.T) {
+	useMockKeyring(t)
+	useTestDB(t)
+	stubNow(t, time.Unix(59, 0))
+	profile := &model.Profile{Name: "dev", MFA: "totp"}
+
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "s3cret"}))
+	require.NoError(t, middleware.SetExpiryToDB(config.KEYRING_SERVICE_NAME, "2999-01-01"))
+	_, err := GetStoredCredential(profile)
+	assert.ErrorContains(t, err, "no TOTP seed stored")
+
+	code, err := SetTOTPSeed(DefaultAccount(), "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	require.NoError(t, StoreCredential(DefaultAccount(), model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: "changed"}))
+	cred, err := GetStoredCredential(profile)
+	require.NoError(t, err, "updating the password keeps the seed")
+	assert.Equal(t, "287082", cred.SecondPassword())
+
+	stubNow(t, time.Unix(1111111109, 0))
+	assert.Equal(t, "081804", cred.SecondPassword(), "the code is generated when it is asked for")
+}
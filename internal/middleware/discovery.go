@@ -0,0 +1,41 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package middleware
+
+import "fmt"
+
+// GetDiscoveredPath returns the cached location of a discovered client binary
+// (e.g. "cisco_vpn", "cisco_gui"); sql.ErrNoRows when nothing is cached.
+func GetDiscoveredPath(name string) (string, error) {
+	db, err := InitDB()
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var path string
+	if err := db.QueryRow(`SELECT path FROM vpn_discovered_path WHERE name = ?`, name).Scan(&path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SetDiscoveredPath caches the location of a discovered client binary.
+func SetDiscoveredPath(name, path string) error {
+	db, err := InitDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+	INSERT INTO vpn_discovered_path (name, path) VALUES (?, ?)
+	ON CONFLICT(name) DO UPDATE SET path = excluded.path, timestamp = CURRENT_TIMESTAMP;`, name, path)
+	if err != nil {
+		return fmt.Errorf("caching discovered path of %s: %w", name, err)
+	}
+	return nil
+}
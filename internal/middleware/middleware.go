@@ -25,8 +25,8 @@ import (
 
 // const dbPath = "~/.vpnctl/vpnctl.db"
 
-// expandPath expands ~ to the user home directory.
-func expandPath(path string) (string, error) {
+// ExpandPath expands ~ to the user home directory.
+func ExpandPath(path string) (string, error) {
 	if strings.HasPrefix(path, "~") {
 		home, err := os.UserHomeDir()
 		if err != nil {
@@ -40,7 +40,7 @@ func expandPath(path string) (string, error) {
 // initDB initializes the SQLite database and creates the helm_charts table if it doesn't exist.
 func InitDB() (*sql.DB, error) {
 	var err error
-	expandedPath, err := expandPath(config.SQLITE_DB_PATH)
+	expandedPath, err := ExpandPath(config.SQLITE_DB_PATH)
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +109,41 @@ func InitDB() (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 
+	query_vpn_session := `
+	CREATE TABLE IF NOT EXISTS vpn_session (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		profile TEXT NOT NULL,
+		backend TEXT NOT NULL,
+		started_at TEXT NOT NULL,
+		ended_at TEXT,
+		end_reason TEXT NOT NULL DEFAULT '',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		error_class TEXT NOT NULL DEFAULT '',
+		client_ip TEXT NOT NULL DEFAULT '',
+		rx_bytes INTEGER NOT NULL DEFAULT 0,
+		tx_bytes INTEGER NOT NULL DEFAULT 0
+	);`
+
+	_, err = DB.Exec(query_vpn_session)
+	if err != nil {
+		DB.Close()
+		return nil, fmt.Errorf("failed to create table: %w", err)
+	}
+
+	query_vpn_discovered_path := `
+	CREATE TABLE IF NOT EXISTS vpn_discovered_path (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE NOT NULL,
+		path TEXT NOT NULL,
+		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	_, err = DB.Exec(query_vpn_discovered_path)
+	if err != nil {
+		DB.Close()
+		return nil, fmt.Errorf("failed to create table: %w", err)
+	}
+
 	query_vpn_latest_version := `
 	CREATE TABLE IF NOT EXISTS vpn_latest_version (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -228,10 +263,11 @@ func SetLastConnectedProfile(profile string) error {
 	db, err := InitDB()
 	if err != nil {
 		logger.Errorf("Error connecting to db: %v", err)
+		return err
 	}
 	defer db.Close()
 
-	now := time.Now().Format(time.RFC3339)
+	now := time.Now().Format("2006-01-02T15:04:05.000Z07:00") // millisecond precision keeps ORDER BY stable for quick switches
 
 	query := `
 	INSERT INTO vpn_profile (profile, last_connected_at) VALUES (?, ?)
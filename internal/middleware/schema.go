@@ -0,0 +1,107 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package middleware
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/goo-apps/vpnctl/config"
+)
+
+// expectedSchema lists the tables vpnctl uses and the columns it reads and writes.
+var expectedSchema = map[string][]string{
+	"vpn_profile":                {"profile", "last_connected_at"},
+	"vpn_user_credential_expiry": {"username", "expiry_date"},
+	"vpn_session": {"profile", "backend", "started_at", "ended_at", "end_reason", "attempts",
+		"error_class", "client_ip", "rx_bytes", "tx_bytes"},
+	"vpn_discovered_path": {"name", "path"},
+	"vpn_latest_version":  {"version"},
+}
+
+// CheckSchema opens the database and verifies that every table vpnctl uses
+// has the expected columns, e.g. after a downgrade or a hand-edited database.
+// Unlike InitDB it creates nothing, so a missing table is reported.
+func CheckSchema() error {
+	db, err := openExistingDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	existing := map[string]bool{}
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		return fmt.Errorf("reading schema: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("reading schema: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	tables := make([]string, 0, len(expectedSchema))
+	for table := range expectedSchema {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var problems []string
+	for _, table := range tables {
+		if !existing[table] {
+			problems = append(problems, fmt.Sprintf("table %s is missing", table))
+			continue
+		}
+		rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+		if err != nil {
+			return fmt.Errorf("reading schema of %s: %w", table, err)
+		}
+		var columns []string
+		for rows.Next() {
+			var (
+				cid, notNull, pk int
+				name, kind       string
+				dflt             any
+			)
+			if err := rows.Scan(&cid, &name, &kind, &notNull, &dflt, &pk); err != nil {
+				rows.Close()
+				return fmt.Errorf("reading schema of %s: %w", table, err)
+			}
+			columns = append(columns, name)
+		}
+		rows.Close()
+
+		for _, column := range expectedSchema[table] {
+			if !slices.Contains(columns, column) {
+				problems = append(problems, fmt.Sprintf("%s.%s is missing", table, column))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("unexpected database schema: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// openExistingDB opens the database without creating it or migrating its tables.
+func openExistingDB() (*sql.DB, error) {
+	path, err := ExpandPath(config.SQLITE_DB_PATH)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	return sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&cache=shared", path))
+}
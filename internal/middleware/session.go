@@ -0,0 +1,126 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package middleware
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+)
+
+// sessionTimeLayout stores session times in UTC with millisecond precision so they sort as text.
+const sessionTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// StartSession records a new session and returns its id. Sessions without an end
+// time are the ones currently active.
+func StartSession(s model.Session) (int64, error) {
+	db, err := InitDB()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var ended any
+	if s.EndedAt != nil {
+		ended = s.EndedAt.UTC().Format(sessionTimeLayout)
+	}
+	query := `
+	INSERT INTO vpn_session (profile, backend, started_at, ended_at, end_reason, attempts, error_class, client_ip, rx_bytes, tx_bytes)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`
+	res, err := db.Exec(query, s.Profile, s.Backend, s.StartedAt.UTC().Format(sessionTimeLayout), ended,
+		s.EndReason, s.Attempts, s.ErrorClass, s.ClientIP, s.RxBytes, s.TxBytes)
+	if err != nil {
+		return 0, fmt.Errorf("recording session: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// EndOpenSessions closes every active session with the given reason. Non-zero
+// byte counters from the backend's last status overwrite the stored ones.
+func EndOpenSessions(reason string, endedAt time.Time, rxBytes, txBytes int64) error {
+	db, err := InitDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	query := `
+	UPDATE vpn_session SET ended_at = ?, end_reason = ?,
+		rx_bytes = CASE WHEN ? > 0 THEN ? ELSE rx_bytes END,
+		tx_bytes = CASE WHEN ? > 0 THEN ? ELSE tx_bytes END
+	WHERE ended_at IS NULL;
+	`
+	if _, err := db.Exec(query, endedAt.UTC().Format(sessionTimeLayout), reason, rxBytes, rxBytes, txBytes, txBytes); err != nil {
+		return fmt.Errorf("ending sessions: %w", err)
+	}
+	return nil
+}
+
+// GetOpenSession returns the active session, or sql.ErrNoRows when there is none.
+func GetOpenSession() (*model.Session, error) {
+	sessions, err := querySessions(`WHERE ended_at IS NULL ORDER BY started_at DESC LIMIT 1`)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &sessions[0], nil
+}
+
+// ListSessions returns the sessions started at or after since, newest first,
+// optionally limited to one profile.
+func ListSessions(since time.Time, profile string) ([]model.Session, error) {
+	where := `WHERE started_at >= ?`
+	args := []any{since.UTC().Format(sessionTimeLayout)}
+	if profile != "" {
+		where += ` AND profile = ?`
+		args = append(args, profile)
+	}
+	return querySessions(where+` ORDER BY started_at DESC`, args...)
+}
+
+func querySessions(clause string, args ...any) ([]model.Session, error) {
+	db, err := InitDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+	SELECT id, profile, backend, started_at, ended_at, end_reason, attempts, error_class, client_ip, rx_bytes, tx_bytes
+	FROM vpn_session `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		var s model.Session
+		var started string
+		var ended sql.NullString
+		if err := rows.Scan(&s.ID, &s.Profile, &s.Backend, &started, &ended, &s.EndReason, &s.Attempts,
+			&s.ErrorClass, &s.ClientIP, &s.RxBytes, &s.TxBytes); err != nil {
+			return nil, fmt.Errorf("reading sessions: %w", err)
+		}
+		if s.StartedAt, err = time.Parse(sessionTimeLayout, started); err != nil {
+			return nil, fmt.Errorf("invalid started_at %q: %w", started, err)
+		}
+		if ended.Valid {
+			at, err := time.Parse(sessionTimeLayout, ended.String)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ended_at %q: %w", ended.String, err)
+			}
+			s.EndedAt = &at
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func useTempDB(t *testing.T) {
+	t.Helper()
+	orig := config.SQLITE_DB_PATH
+	config.SQLITE_DB_PATH = filepath.Join(t.TempDir(), "vpnctl.db")
+	t.Cleanup(func() { config.SQLITE_DB_PATH = orig })
+}
+
+func TestSessions_Lifecycle(t *testing.T) {
+	useTempDB(t)
+	now := time.Now()
+
+	failedAt := now.Add(-2 * time.Hour)
+	_, err := StartSession(model.Session{Profile: "dev", Backend: "cisco", StartedAt: failedAt.Add(-time.Minute),
+		EndedAt: &failedAt, EndReason: model.SessionEndConnectFailed, Attempts: 3, ErrorClass: model.FailureAuth})
+	assert.NoError(t, err)
+
+	id, err := StartSession(model.Session{Profile: "intra", Backend: "cisco", StartedAt: now.Add(-time.Hour), Attempts: 1, ClientIP: "10.0.0.2"})
+	assert.NoError(t, err)
+
+	open, err := GetOpenSession()
+	assert.NoError(t, err)
+	assert.Equal(t, id, open.ID)
+	assert.Nil(t, open.EndedAt)
+
+	assert.NoError(t, EndOpenSessions(model.SessionEndDisconnected, now, 100, 200))
+	_, err = GetOpenSession()
+	assert.Error(t, err)
+
+	sessions, err := ListSessions(now.Add(-24*time.Hour), "")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, "intra", sessions[0].Profile) // newest first
+	assert.Equal(t, model.SessionEndDisconnected, sessions[0].EndReason)
+	assert.Equal(t, int64(100), sessions[0].RxBytes)
+	assert.Equal(t, int64(200), sessions[0].TxBytes)
+	assert.WithinDuration(t, now, *sessions[0].EndedAt, time.Millisecond)
+	assert.Equal(t, model.FailureAuth, sessions[1].ErrorClass)
+
+	sessions, err = ListSessions(now.Add(-24*time.Hour), "dev")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	sessions, err = ListSessions(now.Add(-30*time.Minute), "")
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestCheckSchema(t *testing.T) {
+	useTempDB(t)
+	assert.ErrorContains(t, CheckSchema(), "no such file", "CheckSchema creates nothing")
+
+	db, err := InitDB()
+	assert.NoError(t, err)
+	assert.NoError(t, CheckSchema())
+	_, err = db.Exec(`DROP TABLE vpn_session`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE vpn_session (id INTEGER PRIMARY KEY, profile TEXT, backend TEXT, started_at TEXT)`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`DROP TABLE vpn_latest_version`)
+	assert.NoError(t, err)
+	db.Close()
+
+	err = CheckSchema()
+	assert.ErrorContains(t, err, "vpn_session.ended_at is missing")
+	assert.ErrorContains(t, err, "vpn_session.tx_bytes is missing")
+	assert.ErrorContains(t, err, "table vpn_latest_version is missing")
+}
@@ -5,19 +5,48 @@
 // Licensed under the MIT License. See LICENSE file for details.
 package model
 
+import "time"
+
 // USER_CREDENTIAL represents the structure of user credentials.
 type USER_CREDENTIAL struct {
 	Username       string `json:"username"`
-	Password       string `json:"password"`
-	SecondPassword string `json:"second_password"`
+	Password       Secret `json:"password"`
+	SecondPassword Secret `json:"second_password"`
 	YFlag          string `json:"y_flag"`
 }
 
 type CREDENTIAL_FOR_LOGIN struct {
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password Secret `json:"password"`
 	Push     string `json:"-"`
 	YFlag    string `json:"-"`
+	// SecondFactor generates the second password when it is asked for, e.g. a
+	// TOTP code; Push is sent when it is nil
+	SecondFactor func() string `json:"-"`
+}
+
+// SecondPassword returns the second password to send at the time of the prompt.
+func (c *CREDENTIAL_FOR_LOGIN) SecondPassword() string {
+	if c.SecondFactor != nil {
+		return c.SecondFactor()
+	}
+	return c.Push
+}
+
+// CredentialRecordVersion is the schema version of new credential records.
+const CredentialRecordVersion = 1
+
+// CredentialRecord is the JSON document kept in the credential store. Version 0
+// is the legacy newline-joined entry, migrated when it is read.
+type CredentialRecord struct {
+	Version   int               `json:"version"`
+	Profile   string            `json:"profile,omitempty"` // profile the record belongs to, empty for the default
+	Username  string            `json:"username"`
+	Password  Secret            `json:"password"`
+	MFA       string            `json:"mfa"`              // MFA method the record was enrolled for: none, push or totp
+	Fields    map[string]Secret `json:"fields,omitempty"` // further per-profile secrets, by name
+	CreatedAt time.Time         `json:"created_at"`
+	RotatedAt time.Time         `json:"rotated_at"` // when the password last changed
 }
 
 // Config holds the configuration settings for the application.
@@ -26,8 +55,28 @@ type Config struct {
 		BinaryPath      string `toml:"binary_path"`
 		GuiPath         string `toml:"gui_path"`
 		ConnectionRetry int    `toml:"connection_retry"`
+		AgentAddress    string `toml:"agent_address"` // vpnagentd IPC endpoint, polled after recovery
+		AgentWait       string `toml:"agent_wait"`    // how long to wait for the agent to come back
+		RestartAgent    bool   `toml:"restart_agent"` // restart vpnagentd through the service manager when it is locked
 	} `toml:"vpn"`
 
+	OpenConnect struct {
+		BinaryPath string `toml:"binary_path"`
+		PidFile    string `toml:"pid_file"`
+	} `toml:"openconnect"`
+
+	OpenVPN struct {
+		BinaryPath       string `toml:"binary_path"`
+		ManagementSocket string `toml:"management_socket"`
+		PidFile          string `toml:"pid_file"`
+	} `toml:"openvpn"`
+
+	WireGuard struct {
+		WgQuickPath string `toml:"wg_quick_path"`
+		WgPath      string `toml:"wg_path"`
+		StateFile   string `toml:"state_file"`
+	} `toml:"wireguard"`
+
 	Sqlite struct {
 		Path string `toml:"path"`
 	} `toml:"sqlite"`
@@ -42,15 +91,163 @@ type Config struct {
 		EncryptionKey string `toml:"encryption_key"`
 	} `toml:"keyring"`
 
+	Credential struct {
+		Store string `toml:"store"` // auto, keyring, file or env
+		File  string `toml:"file"`  // encrypted credential file of the file store
+		// PasswordMaxAgeDays is how long a password is valid after it was changed;
+		// 0 learns it from the dates entered on rotation
+		PasswordMaxAgeDays int `toml:"password_max_age_days"`
+	} `toml:"credential"`
+
 	Logger struct {
 		LoggerLevel int `toml:"level"`
 	} `toml:"logger"`
+
+	Daemon struct {
+		PollInterval         string `toml:"poll_interval"`
+		MinReconnectInterval string `toml:"min_reconnect_interval"`
+		MaxReconnectsPerHour int    `toml:"max_reconnects_per_hour"`
+		AuthFailureLimit     int    `toml:"auth_failure_limit"`
+		BreakerCooldown      string `toml:"breaker_cooldown"`
+		Socket               string `toml:"socket"`
+		MetricsAddress       string `toml:"metrics_address"`
+	} `toml:"daemon"`
+
+	Profiles []Profile `toml:"profile"`
+}
+
+// Profile describes a single VPN profile the user can connect to.
+// Profiles are declared as [[profile]] tables in resource.toml.
+type Profile struct {
+	Name        string       `toml:"name" json:"name"`
+	Host        string       `toml:"host" json:"host"` // Cisco host/group, gateway URL, .ovpn path or wg-quick config
+	Aliases     []string     `toml:"aliases" json:"aliases,omitempty"`
+	Backend     string       `toml:"backend" json:"backend"`               // cisco (default), openconnect, openvpn or wireguard
+	AuthGroup   string       `toml:"authgroup" json:"authgroup,omitempty"` // openconnect --authgroup
+	MFA         string       `toml:"mfa" json:"mfa"`                       // none, push or totp
+	Prompts     []PromptRule `toml:"prompt" json:"prompts"`                // [[profile.prompt]] rules, see resource.toml
+	Retry       RetryPolicy  `toml:"retry" json:"retry"`                   // [profile.retry], see resource.toml
+	Probes      []Probe      `toml:"probe" json:"probes,omitempty"`        // [[profile.probe]] checks run after connecting
+	FailOnProbe bool         `toml:"fail_on_probe" json:"fail_on_probe"`   // a failed probe fails the connect attempt
+	// PasswordMaxAgeDays overrides [credential] password_max_age_days for the profile
+	PasswordMaxAgeDays int    `toml:"password_max_age_days" json:"password_max_age_days,omitempty"`
+	Description        string `toml:"description" json:"description,omitempty"`
+}
+
+// Probe checks that something behind the tunnel is reachable once a profile is
+// connected: a TCP dial of host:port, an HTTP GET of a URL answering ExpectStatus,
+// or a DNS lookup of an internal host name.
+type Probe struct {
+	Name         string `toml:"name" json:"name"`
+	Type         string `toml:"type" json:"type"`                             // tcp, http or dns
+	Target       string `toml:"target" json:"target"`                         // host:port, URL or host name
+	ExpectStatus int    `toml:"expect_status" json:"expect_status,omitempty"` // http only, defaults to 200
+	Timeout      string `toml:"timeout" json:"timeout"`                       // Go duration, defaults to 5s
+}
+
+// Probe types.
+const (
+	ProbeTCP  = "tcp"
+	ProbeHTTP = "http"
+	ProbeDNS  = "dns"
+)
+
+// ProbeResult is the outcome of one probe run.
+type ProbeResult struct {
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	Target  string        `json:"target"`
+	OK      bool          `json:"ok"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// PromptRule answers a VPN client prompt matching Pattern (a Go regexp).
+// Answer is one of the keywords username, password, yflag and push, which are
+// taken from the stored credential, or a literal sent as-is.
+type PromptRule struct {
+	Pattern string `toml:"pattern" json:"pattern"`
+	Answer  string `toml:"answer" json:"answer"`
+}
+
+// RetryPolicy decides what happens after a failed connect attempt.
+// On maps a failure class to one of the retry actions; delays are Go durations.
+type RetryPolicy struct {
+	MaxAttempts int               `toml:"max_attempts" json:"max_attempts"`
+	BaseDelay   string            `toml:"base_delay" json:"base_delay"`
+	MaxDelay    string            `toml:"max_delay" json:"max_delay"`
+	On          map[string]string `toml:"on" json:"on"`
+}
+
+// Failure classes of a connect attempt.
+const (
+	FailureAgentLocked = "agent_locked"
+	FailureAuth        = "auth_failed"
+	FailureNetwork     = "network_unreachable"
+	FailureServerBusy  = "server_busy"
+	FailureTimeout     = "timeout"
+	FailureProbe       = "probe_failed" // connected, but a probe failed (profile fail_on_probe)
+	FailureOther       = "other"
+)
+
+// Retry actions a policy can take for a failure class.
+const (
+	RetryActionRetry        = "retry"         // retry with exponential backoff and jitter
+	RetryActionRecoverAgent = "recover_agent" // run the backend's agent recovery, then retry
+	RetryActionAbort        = "abort"         // give up immediately
+)
+
+// Reasons a recorded session ended.
+const (
+	SessionEndDisconnected  = "disconnected"   // vpnctl disconnect
+	SessionEndSwitched      = "switched"       // connect to another profile
+	SessionEndDropped       = "dropped"        // found disconnected without vpnctl ending it
+	SessionEndConnectFailed = "connect_failed" // every attempt failed
+)
+
+// Session is one row of the vpn_session history table.
+type Session struct {
+	ID         int64      `json:"id"`
+	Profile    string     `json:"profile"`
+	Backend    string     `json:"backend"`
+	StartedAt  time.Time  `json:"started_at"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"` // nil while the session is active
+	EndReason  string     `json:"end_reason,omitempty"`
+	Attempts   int        `json:"attempts"`
+	ErrorClass string     `json:"error_class,omitempty"` // model.Failure* class of a failed connect
+	ClientIP   string     `json:"client_ip,omitempty"`
+	RxBytes    int64      `json:"rx_bytes"`
+	TxBytes    int64      `json:"tx_bytes"`
+}
+
+// VPN tunnel states reported by a backend.
+const (
+	StateConnected    = "connected"
+	StateDisconnected = "disconnected"
+	StateConnecting   = "connecting" // also reconnecting/disconnecting transitions
+	StateUnknown      = "unknown"
+)
+
+// VPNState describes the tunnel state reported by a backend.
+type VPNState struct {
+	State          string     `json:"state" yaml:"state"`
+	Profile        string     `json:"profile,omitempty" yaml:"profile,omitempty"` // vpnctl profile, filled from history
+	Host           string     `json:"host,omitempty" yaml:"host,omitempty"`
+	ClientAddress  string     `json:"client_address,omitempty" yaml:"client_address,omitempty"`
+	ConnectedSince *time.Time `json:"connected_since,omitempty" yaml:"connected_since,omitempty"`
+	Notices        []string   `json:"notices,omitempty" yaml:"notices,omitempty"`
+	Raw            string     `json:"-" yaml:"-"` // unparsed client output, kept for display
+
+	// Transfer statistics, only filled by backends with the Stats capability.
+	LatestHandshake *time.Time `json:"latest_handshake,omitempty" yaml:"latest_handshake,omitempty"`
+	RxBytes         int64      `json:"rx_bytes,omitempty" yaml:"rx_bytes,omitempty"`
+	TxBytes         int64      `json:"tx_bytes,omitempty" yaml:"tx_bytes,omitempty"`
 }
 
 // Credential represents a simple structure for storing user credentials.
 type Credential struct {
 	Username string
-	Password string
+	Password Secret
 }
 
 type GitHubRelease struct {
@@ -0,0 +1,47 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package model
+
+// SecretMask replaces a non-empty Secret wherever it is printed.
+const SecretMask = "********"
+
+// Secret is a string that is masked when it is formatted, marshaled to JSON or
+// YAML, or logged through zerolog (which uses String and MarshalJSON). Reveal
+// returns the value for the places that really need it, such as the VPN prompt.
+type Secret string
+
+// Reveal returns the secret in plain text.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String masks the secret for fmt's %v and %s and for loggers.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return SecretMask
+}
+
+// GoString masks the secret for %#v.
+func (s Secret) GoString() string {
+	return `model.Secret("` + s.String() + `")`
+}
+
+// MarshalText masks the secret for encoders using encoding.TextMarshaler.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// MarshalJSON masks the secret in JSON output.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.String() + `"`), nil
+}
+
+// MarshalYAML masks the secret in YAML output.
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
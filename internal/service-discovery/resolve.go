@@ -0,0 +1,89 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+package servicediscovery
+
+import (
+	"os"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/logger"
+)
+
+// Where a resolved client path came from.
+const (
+	SourceConfig    = "config"
+	SourceEnv       = "env"
+	SourceCache     = "cache"
+	SourceDiscovery = "discovery"
+)
+
+// Environment variables overriding the discovered client locations.
+const (
+	EnvVPNBinaryPath = "VPNCTL_VPN_BINARY_PATH"
+	EnvVPNGUIPath    = "VPNCTL_VPN_GUI_PATH"
+)
+
+// configKeys are the configuration keys of the client locations; the VPNCTL_*
+// environment layer sets them too.
+var configKeys = map[string]string{
+	"cisco_vpn": "vpn.binary_path",
+	"cisco_gui": "vpn.gui_path",
+}
+
+// detectVPN and detectGUI are replaced in tests.
+var (
+	detectVPN = DetectCiscoVPNPath
+	detectGUI = DetectCiscoGUIPath
+)
+
+// ResolveCiscoPaths sets config.VPN_BINARY_PATH and config.VPN_GUI_PATH at startup.
+// An explicit [vpn] binary_path/gui_path wins, then the VPNCTL_VPN_BINARY_PATH and
+// VPNCTL_VPN_GUI_PATH environment variables, then the location an earlier run cached
+// in SQLite (while it still exists), then discovery, whose result is cached.
+// A client that cannot be found is only logged: profiles may use other backends.
+func ResolveCiscoPaths() {
+	path, source, err := resolvePath("cisco_vpn", config.VPN_BINARY_PATH, EnvVPNBinaryPath, detectVPN)
+	if err != nil {
+		logger.Warningf("Cisco vpn binary not found, set [vpn] binary_path or %s: %v", EnvVPNBinaryPath, err)
+	}
+	config.VPN_BINARY_PATH, config.VPN_BINARY_SOURCE = path, source
+
+	path, source, err = resolvePath("cisco_gui", config.VPN_GUI_PATH, EnvVPNGUIPath, detectGUI)
+	if err != nil {
+		logger.Warningf("Cisco GUI not found, set [vpn] gui_path or %s: %v", EnvVPNGUIPath, err)
+	}
+	config.VPN_GUI_PATH, config.VPN_GUI_SOURCE = path, source
+}
+
+// resolvePath applies the precedence for one client location.
+func resolvePath(name, configured, envVar string, detect func() (string, error)) (string, string, error) {
+	if configured != "" {
+		if config.OriginOf(configKeys[name]).Source == config.OriginEnv {
+			return configured, SourceEnv, nil
+		}
+		return configured, SourceConfig, nil
+	}
+	if path := os.Getenv(envVar); path != "" {
+		return path, SourceEnv, nil
+	}
+	if path, err := middleware.GetDiscoveredPath(name); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			return path, SourceCache, nil
+		}
+		logger.Infof("Cached location %v of %v is gone, discovering again", path, name)
+	}
+
+	path, err := detect()
+	if err != nil {
+		return "", "", err
+	}
+	logger.Infof("Discovered %v at %v", name, path)
+	if err := middleware.SetDiscoveredPath(name, path); err != nil {
+		logger.Warningf("%v", err)
+	}
+	return path, SourceDiscovery, nil
+}
@@ -0,0 +1,89 @@
+package servicediscovery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goo-apps/vpnctl/config"
+	"github.com/goo-apps/vpnctl/internal/middleware"
+	"github.com/goo-apps/vpnctl/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubDiscovery uses a temporary database and a discovery that finds the given
+// path (or nothing) and counts its runs.
+func stubDiscovery(t *testing.T, found string) *int {
+	t.Helper()
+	logger.InitLogger(false, "")
+	origDB, origDetect := config.SQLITE_DB_PATH, detectVPN
+	config.SQLITE_DB_PATH = filepath.Join(t.TempDir(), "vpnctl.db")
+	runs := 0
+	detectVPN = func() (string, error) {
+		runs++
+		if found == "" {
+			return "", errors.New("vpn binary not found")
+		}
+		return found, nil
+	}
+	t.Cleanup(func() { config.SQLITE_DB_PATH, detectVPN = origDB, origDetect })
+	return &runs
+}
+
+func tempBinary(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vpn")
+	assert.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0755))
+	return path
+}
+
+func TestResolvePath_Precedence(t *testing.T) {
+	discovered := tempBinary(t)
+	runs := stubDiscovery(t, discovered)
+
+	path, source, err := resolvePath("cisco_vpn", "/opt/custom/vpn", EnvVPNBinaryPath, detectVPN)
+	assert.NoError(t, err)
+	assert.Equal(t, "/opt/custom/vpn", path)
+	assert.Equal(t, SourceConfig, source)
+
+	t.Setenv(EnvVPNBinaryPath, "/env/vpn")
+	path, source, _ = resolvePath("cisco_vpn", "", EnvVPNBinaryPath, detectVPN)
+	assert.Equal(t, "/env/vpn", path)
+	assert.Equal(t, SourceEnv, source)
+	assert.Equal(t, 0, *runs)
+
+	t.Setenv(EnvVPNBinaryPath, "")
+	path, source, _ = resolvePath("cisco_vpn", "", EnvVPNBinaryPath, detectVPN)
+	assert.Equal(t, discovered, path)
+	assert.Equal(t, SourceDiscovery, source)
+
+	cached, err := middleware.GetDiscoveredPath("cisco_vpn")
+	assert.NoError(t, err)
+	assert.Equal(t, discovered, cached)
+
+	path, source, _ = resolvePath("cisco_vpn", "", EnvVPNBinaryPath, detectVPN)
+	assert.Equal(t, discovered, path)
+	assert.Equal(t, SourceCache, source)
+	assert.Equal(t, 1, *runs, "the cached location skips discovery")
+}
+
+func TestResolvePath_StaleCache(t *testing.T) {
+	runs := stubDiscovery(t, "")
+	assert.NoError(t, middleware.SetDiscoveredPath("cisco_vpn", filepath.Join(t.TempDir(), "uninstalled")))
+
+	path, _, err := resolvePath("cisco_vpn", "", EnvVPNBinaryPath, detectVPN)
+	assert.Error(t, err)
+	assert.Equal(t, "", path)
+	assert.Equal(t, 1, *runs)
+}
+
+func TestFirstExisting(t *testing.T) {
+	present := tempBinary(t)
+	path, ok := firstExisting([]string{filepath.Join(t.TempDir(), "missing"), present})
+	assert.True(t, ok)
+	assert.Equal(t, present, path)
+
+	_, ok = firstExisting([]string{filepath.Join(t.TempDir(), "missing")})
+	assert.False(t, ok)
+}
@@ -44,11 +44,47 @@ func findVPNOnWindows() (string, error) {
 	return findVPNInPath()
 }
 
+// knownCiscoVPNPaths are the install locations of the Cisco `vpn` CLI on macOS and
+// Linux, Secure Client first and the older AnyConnect second.
+var knownCiscoVPNPaths = []string{
+	"/opt/cisco/secureclient/bin/vpn",
+	"/opt/cisco/anyconnect/bin/vpn",
+}
+
+// knownCiscoGUIPaths are the install locations of the Cisco desktop client per OS.
+var knownCiscoGUIPaths = map[string][]string{
+	"darwin": {
+		"/Applications/Cisco/Cisco Secure Client.app",
+		"/Applications/Cisco/Cisco AnyConnect Secure Mobility Client.app",
+	},
+	"linux": {
+		"/opt/cisco/secureclient/bin/vpnui",
+		"/opt/cisco/anyconnect/bin/vpnui",
+	},
+	"windows": {
+		`C:\Program Files (x86)\Cisco\Cisco Secure Client\UI\csc_ui.exe`,
+		`C:\Program Files (x86)\Cisco\Cisco AnyConnect Secure Mobility Client\vpnui.exe`,
+	},
+}
+
+// firstExisting returns the first of the paths that exists.
+func firstExisting(paths []string) (string, bool) {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
 func findVPNOnMac() (string, error) {
 	// Try PATH first
 	if path, err := findVPNInPath(); err == nil {
 		return path, nil
 	}
+	if path, ok := firstExisting(knownCiscoVPNPaths); ok {
+		return path, nil
+	}
 	// Try mdfind for .app bundles
 	out, err := exec.Command("mdfind", "kMDItemCFBundleIdentifier == 'com.cisco.anyconnect.gui'").Output()
 	if err == nil && len(out) > 0 {
@@ -64,7 +100,13 @@ func findVPNOnMac() (string, error) {
 }
 
 func findVPNOnLinux() (string, error) {
-	return findVPNInPath()
+	if path, err := findVPNInPath(); err == nil {
+		return path, nil
+	}
+	if path, ok := firstExisting(knownCiscoVPNPaths); ok {
+		return path, nil
+	}
+	return "", fmt.Errorf("vpn binary not found in PATH or %s", strings.Join(knownCiscoVPNPaths, ", "))
 }
 
 func DetectCiscoVPNPath() (string, error) {
@@ -79,3 +121,16 @@ func DetectCiscoVPNPath() (string, error) {
 		return "", fmt.Errorf("unsupported OS: %s", runtime.GOOS)
 	}
 }
+
+// DetectCiscoGUIPath finds the Cisco Secure Client (or AnyConnect) desktop client:
+// the .app bundle on macOS, the vpnui/csc_ui binary on Linux and Windows.
+func DetectCiscoGUIPath() (string, error) {
+	paths, ok := knownCiscoGUIPaths[runtime.GOOS]
+	if !ok {
+		return "", fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	}
+	if path, ok := firstExisting(paths); ok {
+		return path, nil
+	}
+	return "", fmt.Errorf("cisco GUI not found in %s", strings.Join(paths, ", "))
+}
@@ -56,7 +56,7 @@ func InitLogger(logToFile bool, logFilePath string) {
 		}
 	}
 
-	multi := zerolog.MultiLevelWriter(writers...)
+	multi := newRedactWriter(writers...)
 	logger := zerolog.New(multi).With().Timestamp().Str("module", "vpnctl").Logger()
 	log = &logger
 }
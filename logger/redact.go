@@ -0,0 +1,96 @@
+// Author: rohan.das
+
+// vpnctl - Cross-platform VPN CLI
+// Copyright (c) 2025 goo-apps (rohan.das1203@gmail.com)
+// Licensed under the MIT License. See LICENSE file for details.
+
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// redactMask replaces a registered secret in the log output.
+const redactMask = "********"
+
+// minSecretLength keeps short values, which would mask ordinary words and
+// numbers, from being registered.
+const minSecretLength = 4
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = map[string]struct{}{}
+	scrubber  = strings.NewReplacer()
+)
+
+// RegisterSecret has every log line scrubbed of value before it is written to the
+// console or the log file. Values shorter than four characters are ignored.
+func RegisterSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if _, ok := secrets[value]; ok {
+		return
+	}
+	secrets[value] = struct{}{}
+
+	// Longest first so a secret containing another is masked whole. zerolog
+	// writes JSON, so the escaped form is scrubbed as well.
+	values := make([]string, 0, len(secrets))
+	for v := range secrets {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	var pairs []string
+	for _, v := range values {
+		pairs = append(pairs, v, redactMask)
+		if quoted, err := json.Marshal(v); err == nil {
+			if escaped := string(quoted[1 : len(quoted)-1]); escaped != v {
+				pairs = append(pairs, escaped, redactMask)
+			}
+		}
+	}
+	scrubber = strings.NewReplacer(pairs...)
+}
+
+// scrub masks the registered secrets in p.
+func scrub(p []byte) []byte {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	if len(secrets) == 0 {
+		return p
+	}
+	return []byte(scrubber.Replace(string(p)))
+}
+
+// redactWriter scrubs the registered secrets from each log line before passing
+// it on.
+type redactWriter struct {
+	out zerolog.LevelWriter
+}
+
+func newRedactWriter(writers ...io.Writer) zerolog.LevelWriter {
+	return redactWriter{out: zerolog.MultiLevelWriter(writers...)}
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	if _, err := w.out.Write(scrub(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w redactWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if _, err := w.out.WriteLevel(level, scrub(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/goo-apps/vpnctl/internal/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret_Masked(t *testing.T) {
+	secret := model.Secret("s3cret")
+	assert.Equal(t, "******** ********", fmt.Sprintf("%v %s", secret, secret))
+	assert.Equal(t, `model.Secret("********")`, fmt.Sprintf("%#v", secret))
+	assert.Equal(t, "s3cret", secret.Reveal())
+	assert.Equal(t, "", model.Secret("").String())
+
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	log.Info().Interface("cred", model.CREDENTIAL_FOR_LOGIN{Username: "alice", Password: secret}).Stringer("password", secret).Msg("")
+	assert.NotContains(t, buf.String(), "s3cret")
+}
+
+func TestRedactWriter(t *testing.T) {
+	RegisterSecret("hunter2!")
+	RegisterSecret(`pa"ss\word`)
+	RegisterSecret("abc") // too short to register
+
+	var buf bytes.Buffer
+	log := zerolog.New(newRedactWriter(&buf))
+	log.Info().Msgf("login with hunter2! and %s for abc", `pa"ss\word`)
+	assert.Equal(t, `{"level":"info","message":"login with ******** and ******** for abc"}`+"\n", buf.String())
+}
@@ -7,10 +7,13 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"text/tabwriter"
 
 	goautobuild "github.com/goo-apps/go-auto-build"
@@ -19,6 +22,7 @@ import (
 	"github.com/goo-apps/vpnctl/internal/handler"
 	"github.com/goo-apps/vpnctl/internal/middleware"
 	"github.com/goo-apps/vpnctl/internal/model"
+	servicediscovery "github.com/goo-apps/vpnctl/internal/service-discovery"
 	"github.com/goo-apps/vpnctl/logger"
 	"golang.org/x/term"
 
@@ -76,36 +80,93 @@ func showHelp() {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "Command\tDescription")
 	fmt.Fprintln(w, "-------\t-----------")
-	fmt.Fprintln(w, "vpnctl status\tShow VPN status")
-	fmt.Fprintln(w, "vpnctl connect intra\tConnect using intra profile")
-	fmt.Fprintln(w, "vpnctl connect dev\tConnect using dev profile")
+	fmt.Fprintln(w, "vpnctl status [--output json|yaml|table]\tShow VPN status (exit 0 connected, 3 disconnected, 4 unknown)")
+	for _, p := range config.VPN_PROFILES {
+		description := p.Description
+		if description == "" {
+			description = fmt.Sprintf("Connect using %s profile", p.Name)
+		}
+		if len(p.Aliases) > 0 {
+			description = fmt.Sprintf("%s (aliases: %s)", description, strings.Join(p.Aliases, ", "))
+		}
+		fmt.Fprintf(w, "vpnctl connect %s\t%s\n", p.Name, description)
+	}
 	fmt.Fprintln(w, "vpnctl disconnect\tDisconnect VPN and kill GUI")
+	fmt.Fprintln(w, "vpnctl daemon [profile]\tKeep a profile connected and serve the control socket")
+	fmt.Fprintln(w, "vpnctl history [--since 7d] [--profile X] [--output csv|json|table]\tShow past VPN sessions")
+	fmt.Fprintln(w, "vpnctl doctor [--json]\tCheck the environment vpnctl depends on")
+	fmt.Fprintln(w, "vpnctl config show [--origin]\tShow the merged configuration and where each value came from")
+	fmt.Fprintln(w, "vpnctl config get <key>\tShow one configuration value, e.g. daemon.poll_interval")
+	fmt.Fprintln(w, "vpnctl config set <key> <value>\tSet a value in ~/.vpnctl/config.toml")
+	fmt.Fprintln(w, "vpnctl config validate [file]\tCheck configuration files for errors")
+	fmt.Fprintln(w, "vpnctl --set <key>=<value> <command>\tOverride a configuration value for one run")
+	fmt.Fprintln(w, "vpnctl export [--out bundle.vpnctl]\tWrite profiles, credentials and history to a passphrase-encrypted bundle")
+	fmt.Fprintln(w, "vpnctl import [--yes] <bundle>\tShow what a bundle adds and merge it into this machine")
 	fmt.Fprintln(w, "vpnctl kill\tKill Cisco Secure Client GUI only")
 	fmt.Fprintln(w, "vpnctl gui\tLaunch Cisco GUI")
-	fmt.Fprintln(w, "vpnctl credential update\tUpdate your credential")
-	fmt.Fprintln(w, "vpnctl credential fetch\tFetch your existing credential")
-	fmt.Fprintln(w, "vpnctl credential remove\tRemove your existing credential")
+	fmt.Fprintln(w, "vpnctl credential list [--output json|table]\tList stored credentials with usernames and expiry, never passwords")
+	fmt.Fprintln(w, "vpnctl credential update [--profile name]\tUpdate your credential, or store one for a single profile")
+	fmt.Fprintln(w, "vpnctl credential fetch [--profile name] [--reveal]\tFetch your existing credential, the password is masked unless revealed")
+	fmt.Fprintln(w, "vpnctl credential rotate [--profile name]\tStore a changed password and reset its expiry")
+	fmt.Fprintln(w, "vpnctl credential totp set|show-code [--profile name]\tStore the TOTP seed of mfa = totp profiles, or show the current code")
+	fmt.Fprintln(w, "vpnctl credential passphrase\tSet the passphrase protecting the credential store")
+	fmt.Fprintln(w, "vpnctl credential remove [--profile name]\tRemove your existing credential, or a profile's own one")
 	fmt.Fprintln(w, "vpnctl help\tShow this help message")
 	w.Flush()
 }
 
+// credentialProfile parses the --profile flag of a credential operation. It is nil
+// without the flag, which selects the default credential.
+func credentialProfile(operation string, args []string) *model.Profile {
+	fs := flag.NewFlagSet("credential "+operation, flag.ExitOnError)
+	name := fs.String("profile", "", "profile whose own credential entry is used")
+	fs.Parse(args)
+	if *name == "" {
+		return nil
+	}
+	profile, err := config.ResolveProfile(*name)
+	if err != nil {
+		logger.Fatalf("%s", err)
+		return nil
+	}
+	return profile
+}
+
 func main() {
 	// Initialize logger: logToFile=true, verbosity=2, file=~/.vpnctl.log
 	logger.InitLogger(true, "")
 	defer logger.Shutdown()
 
+	// global --set key=value flags override every other configuration layer
+	args, ferr := config.ParseFlags(os.Args[1:])
+	if ferr != nil {
+		logger.Fatalf("%s", ferr)
+	}
+	os.Args = append(os.Args[:1], args...)
+
 	// load configuration
 	configPath := os.Getenv("CONFIG_PATH")
 	cerr := config.LoadAllConfigAtOnce(configPath) // loading from embedded config
 	if cerr != nil {
-		logger.Fatalf("failed to load configuration: %s", cerr)
+		if len(os.Args) >= 3 && os.Args[1] == "config" && os.Args[2] == "validate" {
+			path := ""
+			if len(os.Args) >= 4 {
+				path = os.Args[3]
+			}
+			code := vpnctl.ConfigValidate(path)
+			logger.Shutdown()
+			os.Exit(code)
+		}
+		logger.Fatalf("failed to load configuration (run `vpnctl config validate`): %s", cerr)
 	}
 
 	// Initialize the database (ensure it's done before API handlers)
 	_, dberr := middleware.InitDB()
 	if dberr != nil {
 		logger.Errorf("failed to initialize database: %s", dberr)
-		os.Exit(1) // Exit if database initialization fails
+		if len(os.Args) < 2 || (os.Args[1] != "doctor" && os.Args[1] != "config") { // doctor reports it
+			os.Exit(1) // Exit if database initialization fails
+		}
 	}
 
 	if len(os.Args) < 2 {
@@ -116,33 +177,135 @@ func main() {
 		}
 	}
 
-	// _, errrr := servicediscovery.DetectCiscoVPNPath()
-	// if errrr != nil {
-	// 	logger.Warningf("Failed to detect Cisco Secure Client path: %s", errrr)
-	// 	fmt.Println("Please ensure Cisco Secure Client is installed and in your PATH.")
-	// }
+	// resolve the Cisco client: [vpn] config, then env, then cached discovery
+	servicediscovery.ResolveCiscoPaths()
 
 	var err error
 	// Process CLI commands after credentials are set
 	if len(os.Args) >= 2 {
-		var credential *model.CREDENTIAL_FOR_LOGIN
 		cmd := os.Args[1]
 		switch cmd {
 		case "connect":
 			if len(os.Args) < 3 {
-				fmt.Print("Please specify profile: intra or dev")
+				fmt.Printf("Please specify profile: %s", strings.Join(config.ProfileNames(), " or "))
 				return
 			}
-			credential, err = handler.GetOrPromptCredential()
-			if err != nil {
-				logger.Fatalf("Failed to get credentials: %s", err)
+			profile, perr := config.ResolveProfile(os.Args[2])
+			if perr != nil {
+				logger.Fatalf("%s", perr)
+				return
+			}
+			if err = vpnctl.Connect(profile.Name); err != nil {
+				logger.Fatalf("Failed to connect: %s", err)
 				return
 			}
-			vpnctl.Connect(credential, os.Args[2])
 		case "disconnect":
 			vpnctl.DisconnectWithKillPid()
 		case "status":
-			vpnctl.Status()
+			fs := flag.NewFlagSet("status", flag.ExitOnError)
+			output := fs.String("output", "table", "output format: json, yaml or table")
+			fs.Parse(os.Args[2:])
+			code := vpnctl.Status(*output)
+			logger.Shutdown()
+			os.Exit(code)
+		case "daemon":
+			name := ""
+			if len(os.Args) >= 3 {
+				name = os.Args[2]
+			}
+			d, derr := vpnctl.NewDaemon(name)
+			if derr != nil {
+				logger.Fatalf("%s", derr)
+				return
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			err = d.Run(ctx)
+			stop()
+			if err != nil {
+				logger.Fatalf("%s", err)
+				return
+			}
+		case "doctor":
+			fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+			asJSON := fs.Bool("json", false, "print the report as JSON")
+			fs.Parse(os.Args[2:])
+			output := "table"
+			if *asJSON {
+				output = "json"
+			}
+			code := vpnctl.Doctor(output)
+			logger.Shutdown()
+			os.Exit(code)
+		case "config":
+			if len(os.Args) < 3 {
+				fmt.Print("Please specify an operation: show, get, set or validate")
+				return
+			}
+			switch os.Args[2] {
+			case "show":
+				fs := flag.NewFlagSet("config show", flag.ExitOnError)
+				origin := fs.Bool("origin", false, "show where each value came from")
+				fs.Parse(os.Args[3:])
+				vpnctl.ConfigShow(*origin)
+			case "get":
+				if len(os.Args) < 4 {
+					fmt.Print("Usage: vpnctl config get <key>")
+					return
+				}
+				if err := vpnctl.ConfigGet(os.Args[3]); err != nil {
+					logger.Fatalf("%s", err)
+					return
+				}
+			case "set":
+				if len(os.Args) < 5 {
+					fmt.Print("Usage: vpnctl config set <key> <value>")
+					return
+				}
+				if err := vpnctl.ConfigSet(os.Args[3], os.Args[4]); err != nil {
+					logger.Fatalf("%s", err)
+					return
+				}
+			case "validate":
+				path := ""
+				if len(os.Args) >= 4 {
+					path = os.Args[3]
+				}
+				code := vpnctl.ConfigValidate(path)
+				logger.Shutdown()
+				os.Exit(code)
+			default:
+				fmt.Printf("Unknown config operation: %s", os.Args[2])
+			}
+		case "history":
+			fs := flag.NewFlagSet("history", flag.ExitOnError)
+			since := fs.String("since", "7d", "look-back window, e.g. 7d, 12h or 30m")
+			profile := fs.String("profile", "", "only show sessions of this profile")
+			output := fs.String("output", "table", "output format: csv, json or table")
+			fs.Parse(os.Args[2:])
+			if err := vpnctl.History(*since, *profile, *output); err != nil {
+				logger.Fatalf("%s", err)
+				return
+			}
+		case "export":
+			fs := flag.NewFlagSet("export", flag.ExitOnError)
+			out := fs.String("out", "bundle.vpnctl", "file to write the encrypted bundle to")
+			fs.Parse(os.Args[2:])
+			if err := vpnctl.Export(*out); err != nil {
+				logger.Fatalf("Failed to export: %s", err)
+				return
+			}
+		case "import":
+			fs := flag.NewFlagSet("import", flag.ExitOnError)
+			yes := fs.Bool("yes", false, "merge without asking for confirmation")
+			fs.Parse(os.Args[2:])
+			if fs.NArg() != 1 {
+				fmt.Print("Usage: vpnctl import [--yes] <bundle>")
+				return
+			}
+			if err := vpnctl.Import(fs.Arg(0), *yes); err != nil {
+				logger.Fatalf("Failed to import: %s", err)
+				return
+			}
 		case "kill":
 			vpnctl.KillGUI()
 		case "gui":
@@ -155,26 +318,46 @@ func main() {
 		// handler.StoreCredential(profile, credential.Username, credential.Password)
 		case "credential":
 			if len(os.Args) < 3 {
-				fmt.Print("Please specify an operation: fetch or update or delete")
+				fmt.Print("Please specify an operation: list, fetch, update, rotate, totp, passphrase or remove")
 				return
 			}
 
 			switch os.Args[2] {
+			case "list":
+				fs := flag.NewFlagSet("credential list", flag.ExitOnError)
+				output := fs.String("output", "table", "output format: json or table")
+				fs.Parse(os.Args[3:])
+				if err := vpnctl.CredentialList(*output); err != nil {
+					logger.Fatalf("%s", err)
+					return
+				}
 			case "fetch":
-				creds, err := handler.GetCredential()
+				fs := flag.NewFlagSet("credential fetch", flag.ExitOnError)
+				name := fs.String("profile", "", "profile whose own credential entry is used")
+				reveal := fs.Bool("reveal", false, "show the password in plain text after entering it again")
+				fs.Parse(os.Args[3:])
+				var profile *model.Profile
+				if *name != "" {
+					p, err := config.ResolveProfile(*name)
+					if err != nil {
+						logger.Fatalf("%s", err)
+						return
+					}
+					profile = p
+				}
+				account, err := handler.AccountFor(profile)
 				if err != nil {
-					logger.Fatalf("Failed to fetch credential from keyring: %s", err)
+					logger.Fatalf("Failed to fetch credential: %s", err)
 					return
 				}
-				data, err := json.MarshalIndent(creds, "", "  ")
-				if err != nil {
-					fmt.Println("Error marshaling to JSON:", err)
-				} else {
-					fmt.Println(string(data))
+				if err := vpnctl.CredentialFetch(account, *reveal); err != nil {
+					logger.Fatalf("Failed to fetch credential: %s", err)
+					return
 				}
 			case "update":
+				account := handler.ProfileAccount(credentialProfile("update", os.Args[3:]))
 				reader := bufio.NewReader(os.Stdin)
-				fmt.Printf("Enter username for '%s': ", config.KEYRING_SERVICE_NAME)
+				fmt.Printf("Enter username for '%s': ", account)
 				username, _ := reader.ReadString('\n')
 				username = strings.TrimSpace(username)
 
@@ -184,17 +367,104 @@ func main() {
 
 				credential := model.CREDENTIAL_FOR_LOGIN{
 					Username: username,
-					Password: password,
+					Password: model.Secret(password),
 				}
 				// call store function
-				err := handler.StoreCredential(credential)
+				err := handler.StoreCredential(account, credential)
 				if err != nil {
 					logger.Fatalf("Failed to store credential: %s", err)
 					return
 				}
 
+			case "rotate":
+				profile := credentialProfile("rotate", os.Args[3:])
+				account, err := handler.AccountFor(profile)
+				if err != nil {
+					logger.Fatalf("Failed to rotate credential: %s", err)
+					return
+				}
+
+				reader := bufio.NewReader(os.Stdin)
+				username := ""
+				if stored, err := handler.GetCredential(account); err == nil {
+					username = stored.Username
+				} else {
+					fmt.Printf("Enter username for '%s': ", account)
+					username, _ = reader.ReadString('\n')
+					username = strings.TrimSpace(username)
+				}
+				fmt.Print("New password: ")
+				first, _ := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				fmt.Print("Repeat password: ")
+				second, _ := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				if string(first) != string(second) {
+					logger.Fatalf("Passwords do not match")
+					return
+				}
+				changedOn, err := handler.PromptChangeDate(reader)
+				if err != nil {
+					logger.Fatalf("%s", err)
+					return
+				}
+				expiry, err := handler.RotateCredential(profile, model.CREDENTIAL_FOR_LOGIN{Username: username, Password: model.Secret(first)}, changedOn)
+				if err != nil {
+					logger.Fatalf("Failed to rotate credential: %s", err)
+					return
+				}
+				fmt.Printf("Password rotated, it expires on %s\n", expiry.Format("2006-01-02"))
+
+			case "totp":
+				if len(os.Args) < 4 {
+					fmt.Print("Please specify an operation: set or show-code")
+					return
+				}
+				account, err := handler.AccountFor(credentialProfile("totp "+os.Args[3], os.Args[4:]))
+				if err != nil {
+					logger.Fatalf("%s", err)
+					return
+				}
+				switch os.Args[3] {
+				case "set":
+					fmt.Print("TOTP seed (base32 secret or otpauth:// URI): ")
+					seed, _ := term.ReadPassword(int(os.Stdin.Fd()))
+					fmt.Println()
+					code, err := handler.SetTOTPSeed(account, string(seed))
+					if err != nil {
+						logger.Fatalf("Failed to store TOTP seed: %s", err)
+						return
+					}
+					fmt.Printf("TOTP seed stored. Current code: %s, compare it with your authenticator app\n", code)
+				case "show-code":
+					code, remaining, err := handler.TOTPCode(account)
+					if err != nil {
+						logger.Fatalf("Failed to generate TOTP code: %s", err)
+						return
+					}
+					fmt.Printf("%s (valid for %s)\n", code, remaining)
+				default:
+					fmt.Printf("Unknown totp operation: %s", os.Args[3])
+				}
+
+			case "passphrase":
+				fmt.Print("New passphrase (empty removes it from the keyring data key): ")
+				first, _ := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				fmt.Print("Repeat passphrase: ")
+				second, _ := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				if string(first) != string(second) {
+					logger.Fatalf("Passphrases do not match")
+					return
+				}
+				if err := handler.ChangePassphrase(string(first)); err != nil {
+					logger.Fatalf("Failed to set passphrase: %s", err)
+					return
+				}
+
 			case "remove":
-				err := handler.RemoveCredential()
+				err := handler.RemoveCredential(handler.ProfileAccount(credentialProfile("remove", os.Args[3:])))
 				if err != nil {
 					logger.Fatalf("Failed to remove credential: %s", err)
 					return